@@ -1,5 +1,12 @@
 module github.com/fermyon/spin/sdk/go
 
-go 1.17
+go 1.18
 
-require github.com/julienschmidt/httprouter v1.3.0
+require (
+	github.com/julienschmidt/httprouter v1.3.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	google.golang.org/protobuf v1.28.1
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
@@ -0,0 +1,80 @@
+package key_value
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// BatchOp is one operation of a batch read by ApplyBatch. In JSON it is an
+// object of the form
+//
+//	{"op": "set", "key": "user:1", "value": "<base64>"}
+//
+// where op is "get", "set" or "delete", and value is only used by set.
+type BatchOp struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// BatchOpResult is the outcome of one BatchOp. Error is empty if the
+// operation succeeded; Value holds the value read by a successful get.
+type BatchOpResult struct {
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// BatchResult reports the outcome of every operation of a batch, in order,
+// and encodes as {"results": [...], "failed": n}, ready to send back to the
+// client that submitted it.
+type BatchResult struct {
+	Results []BatchOpResult `json:"results"`
+	Failed  int             `json:"failed"`
+}
+
+// ApplyBatch reads a JSON array of operations in the form described by
+// BatchOp from r and applies them to store in order, so that a client can
+// submit several mutations in one HTTP request to a component. An operation
+// that fails does not stop the batch: its error is reported in its result
+// and the next is applied, so the result tells the client exactly which
+// operations took effect. The batch is not atomic.
+//
+// The whole array is read and checked before anything is applied: a
+// malformed body or an unknown op returns an error with nothing applied.
+func ApplyBatch(store KV, r io.Reader) (BatchResult, error) {
+	var ops []BatchOp
+	if err := json.NewDecoder(r).Decode(&ops); err != nil {
+		return BatchResult{}, err
+	}
+	for i, op := range ops {
+		switch op.Op {
+		case "get", "set", "delete":
+		default:
+			return BatchResult{}, fmt.Errorf("operation %d: unknown op %q", i, op.Op)
+		}
+	}
+
+	result := BatchResult{Results: make([]BatchOpResult, len(ops))}
+	for i, op := range ops {
+		res := BatchOpResult{Op: op.Op, Key: op.Key}
+		var err error
+		switch op.Op {
+		case "get":
+			res.Value, err = store.Get(op.Key)
+		case "set":
+			err = store.Set(op.Key, op.Value)
+		case "delete":
+			err = store.Delete(op.Key)
+		}
+		if err != nil {
+			res.Value = nil
+			res.Error = err.Error()
+			result.Failed++
+		}
+		result.Results[i] = res
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package key_value_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestApplyBatch(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("old", []byte("x"))
+
+	// "dmFsdWU=" is "value" in base64.
+	body := `[
+		{"op": "set", "key": "k", "value": "dmFsdWU="},
+		{"op": "get", "key": "k"},
+		{"op": "get", "key": "missing"},
+		{"op": "delete", "key": "old"}
+	]`
+	result, err := key_value.ApplyBatch(store, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 4 || result.Failed != 1 {
+		t.Fatalf("ApplyBatch = %+v, want 4 results with 1 failed", result)
+	}
+	if r := result.Results[1]; r.Error != "" || string(r.Value) != "value" {
+		t.Errorf("get result = %+v, want value", r)
+	}
+	if r := result.Results[2]; r.Error == "" || r.Value != nil {
+		t.Errorf("get of a missing key = %+v, want an error and no value", r)
+	}
+	if ok, _ := store.Exists("old"); ok {
+		t.Error("the operation after a failed one was not applied")
+	}
+}
+
+func TestApplyBatchRejectsBadInput(t *testing.T) {
+	store := kvtest.NewMemStore()
+	for _, body := range []string{
+		`not json`,
+		`[{"op": "set", "key": "k", "value": "dg=="}, {"op": "rename", "key": "k"}]`,
+	} {
+		if _, err := key_value.ApplyBatch(store, strings.NewReader(body)); err == nil {
+			t.Errorf("ApplyBatch(%s) succeeded", body)
+		}
+	}
+	if keys, _ := store.GetKeys(); len(keys) != 0 {
+		t.Errorf("rejected batches wrote %q", keys)
+	}
+}
@@ -0,0 +1,26 @@
+package key_value
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// SetBase64 decodes b64, which must be standard base64 with padding, and
+// stores the decoded bytes at key. Nothing is written if b64 is invalid.
+func SetBase64(store KV, key, b64 string) error {
+	value, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return fmt.Errorf("invalid base64 for %q: %w", key, err)
+	}
+	return store.Set(key, value)
+}
+
+// GetBase64 returns the value stored at key encoded as standard base64 with
+// padding.
+func GetBase64(store KV, key string) (string, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(value), nil
+}
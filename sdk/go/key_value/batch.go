@@ -0,0 +1,145 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GetResult is the outcome of reading one key of a batch.
+type GetResult struct {
+	Key   string
+	Value []byte
+	Err   error
+}
+
+// GetMulti reads each of keys and returns the values of those that exist.
+// Missing keys are omitted from the map. Every other error, including
+// ErrorAccessDenied and ErrorInvalidStore, which point to misconfiguration
+// rather than absent data, aborts the batch and is returned with a nil map,
+// so an absent entry always means the key does not exist.
+func GetMulti(store KV, keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", key, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// GetMultiResult reads each of keys and returns one result per key, in the
+// same order, so that failures for some keys do not hide the values of the
+// others. A missing key has an Err matching ErrorNoSuchKey.
+func GetMultiResult(store KV, keys []string) []GetResult {
+	results := make([]GetResult, len(keys))
+	for i, key := range keys {
+		value, err := store.Get(key)
+		if err != nil {
+			value = nil
+		}
+		results[i] = GetResult{Key: key, Value: value, Err: err}
+	}
+	return results
+}
+
+// GetOrdered reads each of keys and returns their values in the same order.
+// A missing key has a nil value; a key holding an empty value has a non-nil,
+// empty one, so the two can be told apart. Any error other than a missing key
+// stops the batch.
+func GetOrdered(store KV, keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// KeyErrors reports the keys of a batch operation that failed, with the error
+// for each. errors.Is reports whether any of the errors matches its target.
+type KeyErrors map[string]error
+
+func (e KeyErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d keys failed", len(e))
+	for _, key := range keys {
+		fmt.Fprintf(&b, "; %q: %v", key, e[key])
+	}
+	return b.String()
+}
+
+func (e KeyErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteMultiConcurrent deletes keys using up to workers goroutines, for
+// clearing large numbers of keys. Deleting a key that does not exist is not
+// an error. Every key is attempted; if any fail, the returned error is a
+// KeyErrors holding each failure.
+//
+// store must be safe for concurrent use, as Store and the KVs of this
+// package are. A Spin component runs on a single thread and the host
+// completes each call before returning, so deletes through a Store are still
+// made one at a time; the workers only overlap KVs whose operations wait
+// without holding the thread.
+func DeleteMultiConcurrent(store KV, keys []string, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+	work := make(chan string)
+	var mu sync.Mutex
+	failed := KeyErrors{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				if err := store.Delete(key); err != nil {
+					mu.Lock()
+					failed[key] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, key := range keys {
+		work <- key
+	}
+	close(work)
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
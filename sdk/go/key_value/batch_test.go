@@ -0,0 +1,61 @@
+package key_value_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+// deniedStore denies reads of one key.
+type deniedStore struct {
+	*kvtest.MemStore
+	deniedKey string
+}
+
+func (d *deniedStore) Get(key string) ([]byte, error) {
+	if key == d.deniedKey {
+		return []byte{}, key_value.ErrorAccessDenied
+	}
+	return d.MemStore.Get(key)
+}
+
+func TestGetMultiSkipsMissingKeys(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("a", []byte("1"))
+	store.Set("c", []byte("3"))
+
+	values, err := key_value.GetMulti(store, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{"a": []byte("1"), "c": []byte("3")}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("GetMulti = %q, want %q", values, want)
+	}
+}
+
+func TestGetMultiAbortsOnAccessDenied(t *testing.T) {
+	store := &deniedStore{MemStore: kvtest.NewMemStore(), deniedKey: "b"}
+	store.Set("a", []byte("1"))
+	store.Set("b", []byte("2"))
+
+	values, err := key_value.GetMulti(store, []string{"a", "b", "missing"})
+	if !errors.Is(err, key_value.ErrorAccessDenied) {
+		t.Fatalf("GetMulti with a denied key: got error %v, want %v", err, key_value.ErrorAccessDenied)
+	}
+	if values != nil {
+		t.Errorf("GetMulti with a denied key returned values %q", values)
+	}
+}
+
+func TestGetMultiAbortsOnInvalidStore(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Close()
+
+	if _, err := key_value.GetMulti(store, []string{"a"}); !errors.Is(err, key_value.ErrorInvalidStore) {
+		t.Fatalf("GetMulti on a closed store: got error %v, want %v", err, key_value.ErrorInvalidStore)
+	}
+}
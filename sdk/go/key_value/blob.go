@@ -0,0 +1,209 @@
+package key_value
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BlobPrefix is prepended to the keys BlobStore keeps chunks and manifests
+// in. Chunks are stored at BlobPrefix+"chunk:"+hash and manifests at
+// BlobPrefix+"manifest:"+hash.
+const BlobPrefix = "blob:"
+
+// Chunk boundaries are placed where the rolling hash has its low 13 bits
+// clear, giving chunks of about 8 KiB, bounded to the range below.
+const (
+	blobMinChunk = 2 << 10
+	blobMaxChunk = 64 << 10
+	blobMask     = 1<<13 - 1
+)
+
+// blobGear holds the random values mixed into the rolling hash, one per byte
+// value. It is generated from a fixed seed: changing it would move every
+// chunk boundary and defeat deduplication against blobs already stored.
+var blobGear = func() (gear [256]uint64) {
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range gear {
+		// splitmix64
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+	return gear
+}()
+
+// BlobStore keeps large values content-addressed, split into chunks at
+// boundaries chosen by the content itself, so that blobs sharing runs of
+// bytes share the chunks holding them and each distinct chunk is stored
+// once. It saves space when storing many similar blobs, such as successive
+// versions of a document.
+//
+// Each chunk is stored under the hex SHA-256 of its contents. A blob is
+// identified by the hex SHA-256 of its whole contents, and its manifest is a
+// JSON array of its chunk hashes in order. Chunks are never deleted when a
+// manifest is; GCBlobs removes chunks that no manifest refers to.
+type BlobStore struct {
+	store KV
+}
+
+// NewBlobStore returns a BlobStore keeping its chunks and manifests in store.
+func NewBlobStore(store KV) *BlobStore {
+	return &BlobStore{store: store}
+}
+
+// PutBlob stores data and returns the hash identifying it. Chunks already in
+// the store are not written again.
+func (b *BlobStore) PutBlob(data []byte) (string, error) {
+	hashes := []string{}
+	for _, chunk := range splitChunks(data) {
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		hashes = append(hashes, hash)
+
+		key := blobChunkKey(hash)
+		ok, err := b.store.Exists(key)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			if err := b.store.Set(key, chunk); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	manifest, err := json.Marshal(hashes)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if err := b.store.Set(blobManifestKey(hash), manifest); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// GetBlob reassembles the blob identified by hash. It returns ErrorNoSuchKey
+// if there is no manifest for hash or one of its chunks is missing, and an
+// error if the reassembled contents do not match hash.
+func (b *BlobStore) GetBlob(hash string) ([]byte, error) {
+	hashes, err := b.manifest(hash)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, chunkHash := range hashes {
+		chunk, err := b.store.Get(blobChunkKey(chunkHash))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(chunk)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("blob %s is corrupt", hash)
+	}
+	return buf.Bytes(), nil
+}
+
+// DeleteBlob deletes the manifest of the blob identified by hash. Its chunks
+// stay in the store until GCBlobs removes the ones no longer referenced.
+func (b *BlobStore) DeleteBlob(hash string) error {
+	return b.store.Delete(blobManifestKey(hash))
+}
+
+// GCBlobs deletes every chunk that no manifest refers to and returns how many
+// it deleted. It reads every manifest, so its cost grows with the number of
+// blobs. A PutBlob running at the same time can have chunks it has written,
+// but not yet referenced from its manifest, deleted; run GCBlobs when no
+// blobs are being stored.
+func (b *BlobStore) GCBlobs() (int, error) {
+	manifestPrefix := BlobPrefix + "manifest:"
+	manifests, err := keysWithPrefix(b.store, manifestPrefix)
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool)
+	for _, key := range manifests {
+		hashes, err := b.manifest(strings.TrimPrefix(key, manifestPrefix))
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		for _, hash := range hashes {
+			referenced[blobChunkKey(hash)] = true
+		}
+	}
+
+	chunks, err := keysWithPrefix(b.store, BlobPrefix+"chunk:")
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, key := range chunks {
+		if referenced[key] {
+			continue
+		}
+		if err := b.store.Delete(key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (b *BlobStore) manifest(hash string) ([]string, error) {
+	raw, err := b.store.Get(blobManifestKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	if err := json.Unmarshal(raw, &hashes); err != nil {
+		return nil, fmt.Errorf("decoding manifest of blob %s: %w", hash, err)
+	}
+	return hashes, nil
+}
+
+func blobChunkKey(hash string) string {
+	return BlobPrefix + "chunk:" + hash
+}
+
+func blobManifestKey(hash string) string {
+	return BlobPrefix + "manifest:" + hash
+}
+
+// splitChunks splits data at content-defined boundaries using a gear rolling
+// hash, so that an insertion or deletion only changes the chunks around it.
+func splitChunks(data []byte) [][]byte {
+	var chunks [][]byte
+	for len(data) > 0 {
+		n := len(data)
+		if n > blobMinChunk {
+			if n > blobMaxChunk {
+				n = blobMaxChunk
+			}
+			var h uint64
+			for i := blobMinChunk; i < n; i++ {
+				h = h<<1 + blobGear[data[i]]
+				if h&blobMask == 0 {
+					n = i + 1
+					break
+				}
+			}
+		}
+		chunks = append(chunks, data[:n])
+		data = data[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,85 @@
+package key_value_test
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func countChunks(t *testing.T, store key_value.KV) int {
+	t.Helper()
+	keys, err := store.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, key := range keys {
+		if strings.HasPrefix(key, key_value.BlobPrefix+"chunk:") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBlobStoreDeduplicates(t *testing.T) {
+	store := kvtest.NewMemStore()
+	blobs := key_value.NewBlobStore(store)
+
+	original := make([]byte, 256<<10)
+	rand.New(rand.NewSource(1)).Read(original)
+	// The edited copy has a few bytes inserted in the middle.
+	edited := append(append(append([]byte{}, original[:100<<10]...), "inserted"...), original[100<<10:]...)
+
+	originalHash, err := blobs.PutBlob(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterOriginal := countChunks(t, store)
+	editedHash, err := blobs.PutBlob(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added := countChunks(t, store) - afterOriginal; added > 2 {
+		t.Errorf("storing an edited copy added %d chunks to the original's %d", added, afterOriginal)
+	}
+
+	for hash, want := range map[string][]byte{originalHash: original, editedHash: edited} {
+		got, err := blobs.GetBlob(hash)
+		if err != nil {
+			t.Fatalf("GetBlob(%s): %v", hash, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("GetBlob(%s) returned different contents", hash)
+		}
+	}
+
+	if err := blobs.DeleteBlob(editedHash); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := blobs.GCBlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted == 0 || countChunks(t, store) != afterOriginal {
+		t.Errorf("GCBlobs deleted %d chunks, leaving %d; want %d left", deleted, countChunks(t, store), afterOriginal)
+	}
+	if _, err := blobs.GetBlob(originalHash); err != nil {
+		t.Fatalf("GetBlob after GCBlobs: %v", err)
+	}
+}
+
+func TestBlobStoreEmpty(t *testing.T) {
+	blobs := key_value.NewBlobStore(kvtest.NewMemStore())
+	hash, err := blobs.PutBlob(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := blobs.GetBlob(hash)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("GetBlob of an empty blob = %q, %v", got, err)
+	}
+}
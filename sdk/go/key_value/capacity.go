@@ -0,0 +1,129 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithCapacityThreshold returns store wrapped so that CapacityHint warns once
+// it holds at least n keys. Other operations pass straight through to store.
+//
+// The host does not expose how many keys a store can hold or how close it is
+// to returning ErrorStoreTableFull, so n is chosen by the application, for
+// example a little below a limit known from the runtime's configuration.
+func WithCapacityThreshold(store KV, n int) KV {
+	return &capacityThreshold{KV: store, n: n}
+}
+
+type capacityThreshold struct {
+	KV
+	n int
+}
+
+func (c *capacityThreshold) unwrap() KV {
+	return c.KV
+}
+
+// CapacityHint returns how many keys store holds and whether that has reached
+// the threshold set with WithCapacityThreshold, so a component can evict or
+// alert before writes start failing with ErrorStoreTableFull. Without a
+// threshold warning is always false. It lists every key in the store.
+func CapacityHint(store KV) (usedKeys int, warning bool, err error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return 0, false, err
+	}
+	used := len(keys)
+	if c, ok := findOption[*capacityThreshold](store); ok {
+		return used, used >= c.n, nil
+	}
+	return used, false, nil
+}
+
+// Size returns the length in bytes of the value stored at key, or
+// ErrorNoSuchKey if the key does not exist. The host has no way to query a
+// value's size, so Size currently reads the whole value; it exists so that
+// callers deciding whether to process a value have an API that can become
+// cheaper if the host gains one.
+func Size(store KV, key string) (int, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return len(value), nil
+}
+
+// The probe sizes EstimateCapacity starts from and stops at.
+const (
+	capacityProbeMin = 1 << 10
+	capacityProbeMax = 64 << 20
+)
+
+// EstimateCapacity estimates the largest value, in bytes, that store accepts
+// by writing values of increasing size to probeKey until a write fails with
+// ErrorStoreTableFull, ErrValueTooLarge or an io error, and then narrowing
+// down between the last size that was accepted and the first that was not.
+// It returns 0 if not even a 1 KiB value fits, and stops at 64 MiB,
+// reporting that size, if every probe is accepted. The estimate is rounded
+// down to a multiple of 1 KiB.
+//
+// It is a diagnostic for exploring a backend's limits in a deployment, and
+// should only be run deliberately: it makes a dozen or more full writes of up
+// to twice the estimate through the host, and briefly holds a large value at
+// probeKey, which must not exist beforehand and is deleted before returning.
+// The result is approximate, since a backend may fail a write for reasons
+// other than its size.
+func EstimateCapacity(store KV, probeKey string) (maxValueSize int, err error) {
+	exists, err := store.Exists(probeKey)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, fmt.Errorf("probe key %q already exists", probeKey)
+	}
+	defer func() {
+		if delErr := store.Delete(probeKey); delErr != nil && err == nil {
+			err = delErr
+		}
+	}()
+
+	probe := func(size int) (bool, error) {
+		err := store.Set(probeKey, make([]byte, size))
+		if err == nil {
+			return true, nil
+		}
+		if isFullError(err) || errors.Is(err, ErrorIo) || errors.Is(err, ErrValueTooLarge) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	fits, failed := 0, 0
+	for size := capacityProbeMin; size <= capacityProbeMax; size *= 2 {
+		ok, err := probe(size)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			failed = size
+			break
+		}
+		fits = size
+	}
+	if failed == 0 {
+		return fits, nil
+	}
+	for failed-fits > capacityProbeMin {
+		mid := (fits + failed) / 2 / capacityProbeMin * capacityProbeMin
+		ok, err := probe(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			fits = mid
+		} else {
+			failed = mid
+		}
+	}
+	return fits, nil
+}
@@ -0,0 +1,214 @@
+package key_value
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Chain is a KV made of several tiers, such as an in-memory cache in front of
+// a slower backing store. Get returns the value from the first tier that has
+// the key. Set and Delete apply to every tier, fastest first.
+type Chain struct {
+	tiers []KV
+
+	readRepair  bool
+	negativeTTL time.Duration
+	mu          sync.Mutex
+	misses      map[string]time.Time
+	// missGen counts the writes made through the Chain. A miss is only
+	// recorded if no write happened while the tiers were being read, since
+	// the write may have created the key.
+	missGen uint64
+}
+
+var _ KV = (*Chain)(nil)
+
+// A ChainOption configures a Chain.
+type ChainOption func(*Chain)
+
+// WithNegativeCache makes a Chain remember, for ttl, that a key was missing
+// from every tier. Until then Get and Exists report the key as missing
+// without consulting the tiers, so a burst of reads for an absent key costs a
+// single lookup. Setting or deleting the key through the Chain forgets the
+// miss immediately; writes made through any other handle are not seen until
+// ttl has passed. Found values are never cached by this option.
+func WithNegativeCache(ttl time.Duration) ChainOption {
+	return func(c *Chain) {
+		c.negativeTTL = ttl
+		c.misses = make(map[string]time.Time)
+	}
+}
+
+// WithReadRepair makes a Chain copy a value that Get finds in a slower tier
+// into every faster tier that lacked it, so that the cache warms itself
+// again after being flushed without a separate warming pass. Repair writes
+// are best effort: a tier that fails to take the value is left as it was
+// and the read still succeeds.
+func WithReadRepair() ChainOption {
+	return func(c *Chain) {
+		c.readRepair = true
+	}
+}
+
+// NewChain returns a Chain reading from tiers in the given order.
+func NewChain(tiers []KV, opts ...ChainOption) *Chain {
+	c := &Chain{tiers: tiers}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Chain) Get(key string) ([]byte, error) {
+	value, _, err := c.GetWithSource(key)
+	return value, err
+}
+
+// GetWithSource is Get, also reporting whether the value came from the last
+// tier, the backing store, or an earlier one acting as a cache. A miss known
+// from WithNegativeCache is reported as SourceCache.
+func (c *Chain) GetWithSource(key string) ([]byte, Source, error) {
+	if c.knownMissing(key) {
+		return []byte{}, SourceCache, ErrorNoSuchKey
+	}
+	gen := c.missGeneration()
+	for i, tier := range c.tiers {
+		source := SourceCache
+		if i == len(c.tiers)-1 {
+			source = SourceStore
+		}
+		value, err := tier.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err == nil && c.readRepair {
+			for _, faster := range c.tiers[:i] {
+				faster.Set(key, value)
+			}
+		}
+		return value, source, err
+	}
+	c.recordMiss(key, gen)
+	return []byte{}, SourceStore, ErrorNoSuchKey
+}
+
+// Set and Delete forget a recorded miss once the write is done, so that a
+// miss recorded by a read of the tiers during the write is dropped too.
+func (c *Chain) Set(key string, value []byte) error {
+	defer c.forgetMiss(key)
+	for _, tier := range c.tiers {
+		if err := tier.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Chain) Delete(key string) error {
+	defer c.forgetMiss(key)
+	for _, tier := range c.tiers {
+		if err := tier.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Chain) Exists(key string) (bool, error) {
+	if c.knownMissing(key) {
+		return false, nil
+	}
+	gen := c.missGeneration()
+	for _, tier := range c.tiers {
+		exists, err := tier.Exists(key)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	c.recordMiss(key, gen)
+	return false, nil
+}
+
+// GetKeys returns the union of the keys of every tier.
+func (c *Chain) GetKeys() ([]string, error) {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, tier := range c.tiers {
+		tierKeys, err := tier.GetKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range tierKeys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+func (c *Chain) knownMissing(key string) bool {
+	if c.misses == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.misses[key]
+	if ok && time.Now().After(expires) {
+		delete(c.misses, key)
+		return false
+	}
+	return ok
+}
+
+func (c *Chain) missGeneration() uint64 {
+	if c.misses == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.missGen
+}
+
+func (c *Chain) recordMiss(key string, gen uint64) {
+	if c.misses == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.missGen {
+		return
+	}
+	c.misses[key] = time.Now().Add(c.negativeTTL)
+}
+
+func (c *Chain) forgetMiss(key string) {
+	if c.misses == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.missGen++
+	delete(c.misses, key)
+}
+
+// GetFrom reads key from each of stores in turn and returns the first value
+// found along with the store it came from, as when migrating between stores
+// or layering environment-specific settings over a base store. If no store
+// has the key, ErrorNoSuchKey is returned; any other error stops the search.
+func GetFrom(key string, stores ...KV) ([]byte, KV, error) {
+	for _, store := range stores {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return value, store, nil
+	}
+	return nil, nil, ErrorNoSuchKey
+}
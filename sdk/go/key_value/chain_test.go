@@ -0,0 +1,52 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestChainNegativeCache(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	chain := key_value.NewChain([]key_value.KV{kvtest.NewMemStore(), backing}, key_value.WithNegativeCache(time.Hour))
+
+	if _, err := chain.Get("k"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Fatalf("Get(k) = %v, want ErrorNoSuchKey", err)
+	}
+	backing.Set("k", []byte("behind the chain"))
+	if exists, _ := chain.Exists("k"); exists {
+		t.Error("Exists(k) = true, want the miss to have been remembered")
+	}
+
+	if err := chain.Set("k", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := chain.Get("k"); string(got) != "new" {
+		t.Errorf("Get(k) = %q after Set, want %q", got, "new")
+	}
+}
+
+func TestChainNegativeCacheSetDuringGet(t *testing.T) {
+	read := make(chan struct{})
+	tier := &pausingReadStore{MemStore: kvtest.NewMemStore(), read: read, release: make(chan struct{})}
+	chain := key_value.NewChain([]key_value.KV{tier}, key_value.WithNegativeCache(time.Hour))
+
+	done := make(chan struct{})
+	go func() {
+		chain.Get("k")
+		close(done)
+	}()
+	<-read
+	if err := chain.Set("k", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	close(tier.release)
+	<-done
+
+	if got, err := chain.Get("k"); string(got) != "new" {
+		t.Errorf("Get(k) = %q, %v, want %q: a read overlapping the Set recorded a miss", got, err, "new")
+	}
+}
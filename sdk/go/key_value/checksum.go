@@ -0,0 +1,53 @@
+package key_value
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"strconv"
+)
+
+// GetIfChanged reads key and returns its value along with a checksum of it.
+// If the checksum equals lastChecksum, changed is false and the value is nil,
+// so a caller polling for changes can skip decoding data it already has. Pass
+// an empty lastChecksum to always get the value.
+//
+// The value is still read from the host on every call; only the caller's
+// work is saved. The checksum is a 64-bit FNV-1a hash, which is cheap to
+// compute but not collision resistant, and should not be trusted to detect
+// deliberate tampering.
+func GetIfChanged(store KV, key string, lastChecksum string) (value []byte, checksum string, changed bool, err error) {
+	value, err = store.Get(key)
+	if err != nil {
+		return nil, "", false, err
+	}
+	checksum = valueChecksum(value)
+	if checksum == lastChecksum {
+		return nil, checksum, false, nil
+	}
+	return value, checksum, true, nil
+}
+
+func valueChecksum(value []byte) string {
+	h := fnv.New64a()
+	h.Write(value)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// ValueHash returns the SHA-256 hash of the value stored at key as 64
+// lowercase hex digits, for comparing a value against an expected hash or
+// deriving an ETag. ErrorNoSuchKey is returned if the key does not exist.
+// The value is read from the host to be hashed; unlike GetIfChanged's
+// checksum, the hash is collision resistant.
+func ValueHash(store KV, key string) (string, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(value), nil
+}
+
+func sha256Hex(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
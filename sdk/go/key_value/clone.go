@@ -0,0 +1,42 @@
+package key_value
+
+import "sync"
+
+// storeNames remembers the name each open handle was opened with.
+var storeNames = nameRegistry{names: make(map[Store]string)}
+
+type nameRegistry struct {
+	mu    sync.Mutex
+	names map[Store]string
+}
+
+func (r *nameRegistry) add(store Store, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[store] = name
+}
+
+func (r *nameRegistry) remove(store Store) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.names, store)
+}
+
+func (r *nameRegistry) lookup(store Store) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name, ok := r.names[store]
+	return name, ok
+}
+
+// Clone opens a new handle to the store that store was opened from. The new
+// handle is independent of store: closing one does not affect the other, and
+// each must be closed separately. It is useful for giving each goroutine its
+// own handle. ErrorInvalidStore is returned if store is not open.
+func Clone(store Store) (Store, error) {
+	name, ok := storeNames.lookup(store)
+	if !ok {
+		return 0xFFFF_FFFF, ErrorInvalidStore
+	}
+	return Open(name)
+}
@@ -0,0 +1,42 @@
+package key_value
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Codec converts between Go values and the bytes kept in a store.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is a Codec using encoding/json. Its zero value encodes like
+// json.Marshal: compact, with HTML characters escaped. Struct fields are
+// named by their `json` tags as usual.
+type JSONCodec struct {
+	// Indent indents encoded documents by two spaces per level.
+	Indent bool
+	// NoEscapeHTML leaves <, > and & in strings unescaped.
+	NoEscapeHTML bool
+}
+
+var _ Codec = JSONCodec{}
+
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(!c.NoEscapeHTML)
+	if c.Indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode terminates the document with a newline that Marshal does not.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (c JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
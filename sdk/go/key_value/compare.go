@@ -0,0 +1,144 @@
+package key_value
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sort"
+)
+
+// Equal reports whether a and b hold the same keys with the same values, as
+// when checking that a copy matches its source. It lists both stores and then
+// reads each key from both, stopping at the first difference, so it costs
+// O(n) host calls for equal stores. The stores are read one key at a time,
+// not as a snapshot, and concurrent writes can change the result.
+func Equal(a, b KV) (bool, error) {
+	aKeys, err := a.GetKeys()
+	if err != nil {
+		return false, err
+	}
+	bKeys, err := b.GetKeys()
+	if err != nil {
+		return false, err
+	}
+	if len(aKeys) != len(bKeys) {
+		return false, nil
+	}
+	inB := keySet(bKeys)
+	for _, key := range aKeys {
+		if !inB[key] {
+			return false, nil
+		}
+	}
+
+	for _, key := range aKeys {
+		aValue, err := a.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		bValue, err := b.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(aValue, bValue) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Diff compares the contents of a and b, as when checking a migrated or
+// replicated store for drift. It returns, each sorted, the keys only in a,
+// the keys only in b, and the keys in both whose values differ. It lists both
+// stores and reads every key they share from each, so it costs two host calls
+// per shared key; like Equal it does not see a single snapshot of either
+// store.
+func Diff(a, b KV) (added, removed, changed []string, err error) {
+	aKeys, err := a.GetKeys()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	bKeys, err := b.GetKeys()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	inA, inB := keySet(aKeys), keySet(bKeys)
+	for _, key := range bKeys {
+		if !inA[key] {
+			removed = append(removed, key)
+		}
+	}
+
+	for _, key := range aKeys {
+		if !inB[key] {
+			added = append(added, key)
+			continue
+		}
+		aValue, err := a.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			// Deleted from a since it was listed.
+			removed = append(removed, key)
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		bValue, err := b.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			added = append(added, key)
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !bytes.Equal(aValue, bValue) {
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed, nil
+}
+
+// Fingerprint returns a hex SHA-256 digest of the contents of store, so two
+// stores can be compared by fingerprint before paying for a full Diff. Stores
+// with the same keys and values have the same fingerprint regardless of
+// listing order. Fingerprint reads every key and value in the store.
+func Fingerprint(store KV) (string, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(keys)
+
+	// Each key and value is written with its length first, so that no two
+	// different stores serialize to the same bytes.
+	h := sha256.New()
+	var length [8]byte
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		binary.BigEndian.PutUint64(length[:], uint64(len(key)))
+		h.Write(length[:])
+		h.Write([]byte(key))
+		binary.BigEndian.PutUint64(length[:], uint64(len(value)))
+		h.Write(length[:])
+		h.Write(value)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
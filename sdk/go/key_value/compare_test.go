@@ -0,0 +1,87 @@
+package key_value_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestDiff(t *testing.T) {
+	a, b := kvtest.NewMemStore(), kvtest.NewMemStore()
+	for key, value := range map[string]string{"same": "1", "changed": "a", "only-a": "x", "also-a": "y"} {
+		a.Set(key, []byte(value))
+	}
+	for key, value := range map[string]string{"same": "1", "changed": "b", "only-b": "z"} {
+		b.Set(key, []byte(value))
+	}
+
+	added, removed, changed, err := key_value.Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"also-a", "only-a"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %q, want %q", added, want)
+	}
+	if want := []string{"only-b"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %q, want %q", removed, want)
+	}
+	if want := []string{"changed"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+
+	if equal, err := key_value.Equal(a, b); err != nil || equal {
+		t.Errorf("Equal(a, b) = %v, %v, want false", equal, err)
+	}
+	c := kvtest.NewMemStore()
+	if _, err := key_value.CopyStoreMapped(c, a, func(key string) (string, bool) { return key, true }); err != nil {
+		t.Fatal(err)
+	}
+	if equal, err := key_value.Equal(a, c); err != nil || !equal {
+		t.Errorf("Equal(a, copy of a) = %v, %v, want true", equal, err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]string
+		want bool
+	}{
+		{"equal", map[string]string{"x": "1", "y": "2"}, map[string]string{"x": "1", "y": "2"}, true},
+		{"both empty", nil, nil, true},
+		{"different value", map[string]string{"x": "1", "y": "2"}, map[string]string{"x": "1", "y": "3"}, false},
+		{"empty and non-empty value", map[string]string{"x": ""}, map[string]string{"x": "1"}, false},
+		{"key only in a", map[string]string{"x": "1", "y": "2"}, map[string]string{"x": "1"}, false},
+		{"key only in b", map[string]string{"x": "1"}, map[string]string{"x": "1", "y": "2"}, false},
+		{"different keys of the same count", map[string]string{"x": "1", "y": "2"}, map[string]string{"x": "1", "z": "2"}, false},
+		{"one empty", map[string]string{"x": "1"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := kvtest.NewMemStore(), kvtest.NewMemStore()
+			for key, value := range tt.a {
+				a.Set(key, []byte(value))
+			}
+			for key, value := range tt.b {
+				b.Set(key, []byte(value))
+			}
+			if got, err := key_value.Equal(a, b); err != nil || got != tt.want {
+				t.Errorf("Equal(a, b) = %v, %v, want %v", got, err, tt.want)
+			}
+			if got, err := key_value.Equal(b, a); err != nil || got != tt.want {
+				t.Errorf("Equal(b, a) = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqualError(t *testing.T) {
+	a, b := kvtest.NewMemStore(), kvtest.NewMemStore()
+	b.Close()
+	if _, err := key_value.Equal(a, b); !errors.Is(err, key_value.ErrorInvalidStore) {
+		t.Errorf("Equal with a closed store = %v, want ErrorInvalidStore", err)
+	}
+}
@@ -0,0 +1,126 @@
+package key_value
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+// DeleteIf deletes key only if its current value equals expected, and
+// reports whether it did. A missing key does not equal any value, so false is
+// returned without error. The comparison and the delete are separate host
+// calls, so a write from another instance can land in between.
+func DeleteIf(store KV, key string, expected []byte) (bool, error) {
+	defer lockKey(store, key)()
+
+	value, err := store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(value, expected) {
+		return false, nil
+	}
+	if err := store.Delete(key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetAndDelete deletes key and returns the value it held, and false if it did
+// not exist. The read and the delete are separate host calls, so another
+// instance can read the same value before it is deleted.
+func GetAndDelete(store KV, key string) ([]byte, bool, error) {
+	defer lockKey(store, key)()
+
+	value, err := store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := store.Delete(key); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// GetIfExists returns the value stored at key and true, or false if the key
+// does not exist. It makes a single Get and interprets ErrorNoSuchKey, and is
+// the replacement for calling Exists and then Get, which costs two host
+// calls and can see the key deleted in between. Unlike GetOK it does not
+// interpret the sentinel stored by SetNil.
+func GetIfExists(store KV, key string) ([]byte, bool, error) {
+	value, err := store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Claim takes one item from the work set of keys starting with prefix,
+// deleting it with GetAndDelete, and returns it, so that competing workers
+// sharing a store each take different pending jobs. A key deleted by another
+// worker after it was listed is skipped in favour of the next. ok is false if
+// the work set is empty.
+//
+// Exclusivity is best-effort: GetAndDelete is not atomic on the host, so two
+// workers claiming at the same moment can occasionally take the same item.
+func Claim(store KV, prefix string) (key string, value []byte, ok bool, err error) {
+	keys, err := keysWithPrefix(store, prefix)
+	if err != nil {
+		return "", nil, false, err
+	}
+	for _, key := range keys {
+		value, ok, err := GetAndDelete(store, key)
+		if err != nil {
+			return "", nil, false, err
+		}
+		if ok {
+			return key, value, true, nil
+		}
+	}
+	return "", nil, false, nil
+}
+
+// SetDefaults writes each of defaults whose key does not exist, leaving keys
+// that already exist untouched, and returns how many it wrote. It suits
+// seeding a configuration store on first start without overwriting values
+// set since. Each key is checked and then written with separate host calls,
+// so a value written by another instance in between can be overwritten with
+// the default.
+func SetDefaults(store KV, defaults map[string][]byte) (int, error) {
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	written := 0
+	for _, key := range keys {
+		ok, err := setIfAbsent(store, key, defaults[key])
+		if err != nil {
+			return written, err
+		}
+		if ok {
+			written++
+		}
+	}
+	return written, nil
+}
+
+func setIfAbsent(store KV, key string, value []byte) (bool, error) {
+	defer lockKey(store, key)()
+
+	exists, err := store.Exists(key)
+	if err != nil || exists {
+		return false, err
+	}
+	return true, store.Set(key, value)
+}
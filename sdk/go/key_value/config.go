@@ -0,0 +1,59 @@
+package key_value
+
+import (
+	"sync"
+	"time"
+)
+
+// Config is a typed configuration value kept as a JSON document at one key.
+// The decoded value is cached, and the key is checked for changes at most
+// once per refresh interval; the document is decoded again only when its
+// checksum has changed.
+//
+// Documents are decoded with encoding/json, honouring the `json` struct tags
+// of T. A value written to the store can go unseen for up to the refresh
+// interval.
+type Config[T any] struct {
+	store   KV
+	key     string
+	refresh time.Duration
+
+	mu       sync.Mutex
+	value    T
+	checksum string
+	checked  time.Time
+}
+
+// NewConfig returns a Config for the document at key in store, rechecked at
+// most once per refresh. A zero refresh checks the store on every Get.
+func NewConfig[T any](store KV, key string, refresh time.Duration) *Config[T] {
+	return &Config[T]{store: store, key: key, refresh: refresh}
+}
+
+// Get returns the configuration, reloading it first if the refresh interval
+// has passed and the stored document has changed. If reloading fails the
+// error is returned and the next Get tries again.
+func (c *Config[T]) Get() (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checksum != "" && time.Since(c.checked) < c.refresh {
+		return c.value, nil
+	}
+	value, checksum, changed, err := GetIfChanged(c.store, c.key, c.checksum)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if changed {
+		var decoded T
+		if err := jsonCodecFor(c.store).Unmarshal(value, &decoded); err != nil {
+			var zero T
+			return zero, err
+		}
+		c.value = decoded
+		c.checksum = checksum
+	}
+	c.checked = time.Now()
+	return c.value, nil
+}
@@ -0,0 +1,75 @@
+package key_value_test
+
+import (
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestConfigPointerGCVersions(t *testing.T) {
+	store := kvtest.NewMemStore()
+	config := key_value.NewConfigPointer(store, "app")
+	for i := 0; i < 5; i++ {
+		if _, err := config.Publish([]byte("config")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := config.Activate("v2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"app:vnext", "app:v007", "app:v3-draft"} {
+		store.Set(key, []byte("not a version"))
+	}
+
+	deleted, err := config.GCVersions(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("GCVersions(2) deleted %d versions, want 2", deleted)
+	}
+	for key, want := range map[string]bool{
+		"app:v1":       false,
+		"app:v2":       true,
+		"app:v3":       false,
+		"app:v4":       true,
+		"app:v5":       true,
+		"app:vnext":    true,
+		"app:v007":     true,
+		"app:v3-draft": true,
+	} {
+		if exists, _ := store.Exists(key); exists != want {
+			t.Errorf("%s exists = %t after GCVersions(2), want %t", key, exists, want)
+		}
+	}
+	current, err := config.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != "config" {
+		t.Errorf("Current() = %q after GCVersions, want %q", current, "config")
+	}
+
+	if deleted, err := config.GCVersions(2); err != nil || deleted != 0 {
+		t.Errorf("second GCVersions(2) = %d, %v, want 0, nil", deleted, err)
+	}
+}
+
+func TestConfigPointerGCVersionsNoneActive(t *testing.T) {
+	store := kvtest.NewMemStore()
+	config := key_value.NewConfigPointer(store, "app")
+	for i := 0; i < 3; i++ {
+		if _, err := config.Publish([]byte("config")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := config.GCVersions(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Errorf("GCVersions(0) deleted %d versions, want 3", deleted)
+	}
+}
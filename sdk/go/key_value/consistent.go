@@ -0,0 +1,261 @@
+package key_value
+
+import (
+	"errors"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ErrNoStores is returned by the operations of a ConsistentStore that has no
+// stores on its ring, or a ShardedStore that has no shards.
+var ErrNoStores = errors.New("no stores to hold the key")
+
+// consistentReplicas is the number of points each store has on the hash ring.
+// More points spread keys more evenly across stores.
+const consistentReplicas = 64
+
+// ConsistentStore is a KV that spreads its keys across named stores using
+// consistent hashing. Unlike ShardedStore, adding or removing a store only
+// changes the owner of roughly 1/n of the keys, so capacity can be grown
+// without moving every key.
+//
+// AddStore and RemoveStore change which store owns each key immediately, but
+// do not move any data: until Rebalance is called, keys whose owner has
+// changed read as missing.
+type ConsistentStore struct {
+	mu       sync.RWMutex
+	stores   map[string]KV
+	ring     []ringPoint
+	draining []KV
+
+	rebalanceMu sync.Mutex
+}
+
+type ringPoint struct {
+	hash uint32
+	name string
+}
+
+var _ KV = (*ConsistentStore)(nil)
+
+// NewConsistentStore returns a ConsistentStore over stores, keyed by a name
+// that identifies each store on the hash ring. A store must keep the same name
+// across restarts for keys to be found again.
+func NewConsistentStore(stores map[string]KV) *ConsistentStore {
+	c := &ConsistentStore{stores: make(map[string]KV)}
+	for name, store := range stores {
+		c.stores[name] = store
+	}
+	c.buildRing()
+	return c
+}
+
+// AddStore adds store to the ring under name, replacing any store already
+// using that name. Call Rebalance to move the keys it now owns onto it.
+func (c *ConsistentStore) AddStore(name string, store KV) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.stores[name]; ok && !sameStore(old, store) {
+		c.draining = append(c.draining, old)
+	}
+	c.stores[name] = store
+	c.buildRing()
+}
+
+// RemoveStore removes the store called name from the ring. The store's keys
+// are moved to their new owners, and the store itself forgotten, by the next
+// call to Rebalance.
+func (c *ConsistentStore) RemoveStore(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.stores[name]; ok {
+		c.draining = append(c.draining, old)
+		delete(c.stores, name)
+		c.buildRing()
+	}
+}
+
+// Rebalance moves every key that is not held by its owner onto it, and
+// returns the number of keys moved. It lists every store, including removed
+// ones that have not been drained yet, and moves keys one at a time with a
+// Get, a Set and a Delete, so its cost grows with the total number of keys
+// rather than the number that move. A key is left where it is when its owner
+// is the same store it is held by, as when a store is re-added under a new
+// name.
+//
+// The ring is read once at the start, and the store stays usable while keys
+// are copied. Rebalance is not atomic: a key written while it is being moved
+// can be overwritten with the old value, stores added or removed meanwhile
+// are only accounted for by the next call, and if Rebalance fails part way
+// through the keys moved so far stay moved; calling it again resumes the
+// work.
+func (c *ConsistentStore) Rebalance() (int, error) {
+	c.rebalanceMu.Lock()
+	defer c.rebalanceMu.Unlock()
+
+	// Removed stores have no name, so every key they hold is moved.
+	type source struct {
+		name  string
+		store KV
+	}
+	c.mu.RLock()
+	ring := append([]ringPoint(nil), c.ring...)
+	stores := make(map[string]KV, len(c.stores))
+	sources := make([]source, 0, len(c.stores)+len(c.draining))
+	for name, store := range c.stores {
+		stores[name] = store
+		sources = append(sources, source{name, store})
+	}
+	drained := len(c.draining)
+	for _, store := range c.draining {
+		sources = append(sources, source{"", store})
+	}
+	c.mu.RUnlock()
+
+	moved := 0
+	for _, src := range sources {
+		keys, err := src.store.GetKeys()
+		if err != nil {
+			return moved, err
+		}
+		for _, key := range keys {
+			name, err := ownerName(ring, key)
+			if err != nil {
+				return moved, err
+			}
+			owner := stores[name]
+			if name == src.name || sameStore(owner, src.store) {
+				continue
+			}
+			value, err := src.store.Get(key)
+			if errors.Is(err, ErrorNoSuchKey) {
+				continue
+			}
+			if err != nil {
+				return moved, err
+			}
+			if err := owner.Set(key, value); err != nil {
+				return moved, err
+			}
+			if err := src.store.Delete(key); err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+
+	// Keep any store removed since the ring was read for the next call.
+	c.mu.Lock()
+	c.draining = append([]KV(nil), c.draining[drained:]...)
+	c.mu.Unlock()
+	return moved, nil
+}
+
+// Store returns the store that owns key, or ErrNoStores if there are none.
+func (c *ConsistentStore) Store(key string) (KV, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	name, err := ownerName(c.ring, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.stores[name], nil
+}
+
+func ownerName(ring []ringPoint, key string) (string, error) {
+	if len(ring) == 0 {
+		return "", ErrNoStores
+	}
+	h := ringHash(key)
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].name, nil
+}
+
+// sameStore reports whether a and b are the same store. Stores whose type
+// cannot be compared are never the same.
+func sameStore(a, b KV) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+func (c *ConsistentStore) buildRing() {
+	c.ring = c.ring[:0]
+	for name := range c.stores {
+		for i := 0; i < consistentReplicas; i++ {
+			c.ring = append(c.ring, ringPoint{hash: ringHash(name + "#" + strconv.Itoa(i)), name: name})
+		}
+	}
+	sort.Slice(c.ring, func(i, j int) bool {
+		if c.ring[i].hash != c.ring[j].hash {
+			return c.ring[i].hash < c.ring[j].hash
+		}
+		return c.ring[i].name < c.ring[j].name
+	})
+}
+
+func ringHash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func (c *ConsistentStore) Get(key string) ([]byte, error) {
+	store, err := c.Store(key)
+	if err != nil {
+		return []byte{}, err
+	}
+	return store.Get(key)
+}
+
+func (c *ConsistentStore) Set(key string, value []byte) error {
+	store, err := c.Store(key)
+	if err != nil {
+		return err
+	}
+	return store.Set(key, value)
+}
+
+func (c *ConsistentStore) Delete(key string) error {
+	store, err := c.Store(key)
+	if err != nil {
+		return err
+	}
+	return store.Delete(key)
+}
+
+func (c *ConsistentStore) Exists(key string) (bool, error) {
+	store, err := c.Store(key)
+	if err != nil {
+		return false, err
+	}
+	return store.Exists(key)
+}
+
+// GetKeys returns the keys of every store on the ring. Keys still held by a
+// removed store that has not been drained by Rebalance are not included.
+func (c *ConsistentStore) GetKeys() ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var keys []string
+	for _, store := range c.stores {
+		storeKeys, err := store.GetKeys()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, storeKeys...)
+	}
+	return keys, nil
+}
@@ -0,0 +1,90 @@
+package key_value_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestConsistentStoreRebalance(t *testing.T) {
+	store := key_value.NewConsistentStore(map[string]key_value.KV{
+		"a": kvtest.NewMemStore(),
+		"b": kvtest.NewMemStore(),
+	})
+	kvtest.RoundTripTest(t, store)
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := store.Set(key, []byte(key)); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+	}
+
+	store.AddStore("c", kvtest.NewMemStore())
+	moved, err := store.Rebalance()
+	if err != nil {
+		t.Fatalf("Rebalance after AddStore: %v", err)
+	}
+	if moved == 0 || moved > n/2 {
+		t.Errorf("Rebalance after AddStore moved %d of %d keys", moved, n)
+	}
+
+	store.RemoveStore("a")
+	if _, err := store.Rebalance(); err != nil {
+		t.Fatalf("Rebalance after RemoveStore: %v", err)
+	}
+
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		value, err := store.Get(key)
+		if err != nil || string(value) != key {
+			t.Fatalf("Get(%q) = %q, %v after rebalancing", key, value, err)
+		}
+	}
+	keys, err := store.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != n {
+		t.Errorf("GetKeys returned %d keys, want %d", len(keys), n)
+	}
+}
+
+func TestConsistentStoreRebalanceRenamedStore(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.NewConsistentStore(map[string]key_value.KV{"a": backing})
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		store.Set(key, []byte(key))
+	}
+
+	store.AddStore("b", backing)
+	store.RemoveStore("a")
+	moved, err := store.Rebalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 0 {
+		t.Errorf("Rebalance moved %d keys within the same store, want 0", moved)
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if value, err := store.Get(key); err != nil || string(value) != key {
+			t.Fatalf("Get(%q) = %q, %v after renaming the store", key, value, err)
+		}
+	}
+}
+
+func TestConsistentStoreNoStores(t *testing.T) {
+	store := key_value.NewConsistentStore(nil)
+	if err := store.Set("k", nil); !errors.Is(err, key_value.ErrNoStores) {
+		t.Errorf("Set with no stores: %v, want ErrNoStores", err)
+	}
+	if _, err := store.Get("k"); !errors.Is(err, key_value.ErrNoStores) {
+		t.Errorf("Get with no stores: %v, want ErrNoStores", err)
+	}
+}
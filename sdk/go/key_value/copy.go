@@ -0,0 +1,38 @@
+package key_value
+
+import "errors"
+
+// CopyStoreMapped copies entries from src to dst, storing each under the key
+// returned by mapKey and skipping keys for which it returns false. It returns
+// how many entries were copied.
+//
+// Existing keys in dst are overwritten. If mapKey sends several source keys
+// to the same destination key, the one src lists last wins. Keys deleted
+// from src during the copy are skipped. The copy stops at the first error,
+// leaving the entries copied so far in place.
+func CopyStoreMapped(dst, src KV, mapKey func(string) (string, bool)) (int, error) {
+	keys, err := src.GetKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, key := range keys {
+		to, ok := mapKey(key)
+		if !ok {
+			continue
+		}
+		value, err := src.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return copied, err
+		}
+		if err := dst.Set(to, value); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
@@ -0,0 +1,31 @@
+package key_value
+
+// WithCopyOnRead returns store wrapped so that every value returned by Get is
+// a fresh copy owned by the caller, safe to modify and to keep. Other
+// operations pass straight through to store.
+//
+// Store and kvtest.MemStore already return owned values from Get, as does
+// every KV in this package that reads through to one of them; the option is
+// for KVs that may hand out a slice they also keep, such as a cache written
+// outside this package. The other read paths are not affected: GetUnsafe
+// takes a Store, not a KV, and always aliases memory that must not be
+// modified, and GetAppend writes into the caller's own buffer.
+func WithCopyOnRead(store KV) KV {
+	return &copyOnRead{KV: store}
+}
+
+type copyOnRead struct {
+	KV
+}
+
+func (c *copyOnRead) unwrap() KV {
+	return c.KV
+}
+
+func (c *copyOnRead) Get(key string) ([]byte, error) {
+	value, err := c.KV.Get(key)
+	if err != nil {
+		return value, err
+	}
+	return append([]byte{}, value...), nil
+}
@@ -0,0 +1,89 @@
+package key_value
+
+import (
+	"sync"
+	"time"
+)
+
+// CounterBuffer accumulates counter increments in memory and applies each
+// counter's total to the store with a single Increment when flushed, turning
+// many increments per request into one write per counter.
+//
+// Like WriteBehind, buffering trades durability for fewer host calls: counts
+// not yet flushed are lost if the component crashes or returns before Close
+// is called, and are invisible to readers of the store until flushed. A Spin
+// component should call Close, or Flush, before its handler returns.
+type CounterBuffer struct {
+	store KV
+
+	mu      sync.Mutex
+	pending map[string]int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCounterBuffer returns a CounterBuffer that increments counters in
+// store. Pending counts are flushed every flushInterval, if it is positive,
+// and when Flush or Close is called.
+func NewCounterBuffer(store KV, flushInterval time.Duration) *CounterBuffer {
+	b := &CounterBuffer{
+		store:   store,
+		pending: make(map[string]int64),
+		stop:    make(chan struct{}),
+	}
+	if flushInterval > 0 {
+		go b.run(flushInterval)
+	}
+	return b
+}
+
+func (b *CounterBuffer) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.Flush()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// Add adds delta to the pending count for key.
+func (b *CounterBuffer) Add(key string, delta int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[key] += delta
+}
+
+// Flush applies every pending count to the store and returns the first
+// error encountered. Counts that fail to apply stay pending and are retried
+// by the next flush.
+func (b *CounterBuffer) Flush() error {
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = make(map[string]int64)
+	b.mu.Unlock()
+
+	var first error
+	for key, delta := range batch {
+		if delta == 0 {
+			continue
+		}
+		if _, err := Increment(b.store, key, delta); err != nil {
+			b.Add(key, delta)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// Close stops the periodic flush and flushes everything pending.
+func (b *CounterBuffer) Close() error {
+	b.stopOnce.Do(func() { close(b.stop) })
+	return b.Flush()
+}
@@ -0,0 +1,79 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestCounterBuffer(t *testing.T) {
+	store := kvtest.NewMemStore()
+	key_value.SetInt64(store, "hits", 5)
+	b := key_value.NewCounterBuffer(store, 0)
+
+	for i := 0; i < 10; i++ {
+		b.Add("hits", 1)
+	}
+	b.Add("misses", 2)
+	b.Add("misses", -2)
+	if n, _ := key_value.GetInt64(store, "hits"); n != 5 {
+		t.Errorf("hits before Flush = %d, want 5", n)
+	}
+
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := key_value.GetInt64(store, "hits"); err != nil || n != 15 {
+		t.Errorf("hits after Flush = %d, %v, want 15", n, err)
+	}
+	if ok, _ := store.Exists("misses"); ok {
+		t.Error("a net-zero count was written")
+	}
+
+	b.Add("hits", 1)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := key_value.GetInt64(store, "hits"); n != 16 {
+		t.Errorf("hits after Close = %d, want 16", n)
+	}
+}
+
+func TestCounterBufferRetriesFailedFlush(t *testing.T) {
+	store := &failingStore{MemStore: kvtest.NewMemStore(), failKey: "hits"}
+	b := key_value.NewCounterBuffer(store, 0)
+	b.Add("hits", 3)
+
+	if err := b.Flush(); !errors.Is(err, errInjected) {
+		t.Fatalf("Flush = %v, want the write's error", err)
+	}
+	store.failKey = ""
+	b.Add("hits", 1)
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := key_value.GetInt64(store, "hits"); err != nil || n != 4 {
+		t.Errorf("hits after retry = %d, %v, want 4", n, err)
+	}
+}
+
+func TestCounterBufferPeriodicFlush(t *testing.T) {
+	store := kvtest.NewMemStore()
+	b := key_value.NewCounterBuffer(store, 10*time.Millisecond)
+	defer b.Close()
+	b.Add("hits", 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if n, _ := key_value.GetInt64(store, "hits"); n == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pending count was not flushed periodically")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
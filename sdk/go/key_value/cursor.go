@@ -0,0 +1,41 @@
+package key_value
+
+import "sort"
+
+// Cursor pages through a sorted snapshot of a store's keys, so that pages
+// stay stable while the store changes, which the host's unspecified listing
+// order does not guarantee across calls.
+type Cursor struct {
+	keys []string
+	pos  int
+}
+
+// OpenCursor lists the keys of store once, sorts them, and returns a Cursor
+// over them. Keys created after OpenCursor returns are never seen by the
+// cursor, and keys deleted since are still returned by Next, so callers
+// reading the values must expect ErrorNoSuchKey.
+func OpenCursor(store KV) (*Cursor, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return &Cursor{keys: keys}, nil
+}
+
+// Next returns up to limit keys following those already returned, and an
+// empty slice once every key has been returned.
+func (c *Cursor) Next(limit int) []string {
+	end := c.pos + limit
+	if end > len(c.keys) || limit < 0 {
+		end = len(c.keys)
+	}
+	page := c.keys[c.pos:end]
+	c.pos = end
+	return page
+}
+
+// Remaining returns the number of keys not yet returned by Next.
+func (c *Cursor) Remaining() int {
+	return len(c.keys) - c.pos
+}
@@ -0,0 +1,55 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEnum is returned when a value is not one of an EnumKey's allowed
+// values.
+var ErrInvalidEnum = errors.New("value not allowed")
+
+// EnumKey is a key whose value is restricted to a fixed set of strings, such
+// as the states "pending", "active" and "closed". Values are stored as plain
+// text.
+//
+// The restriction is enforced by this SDK when writing through Set, and
+// checked again by Get; the store itself accepts any value, so writers that
+// bypass EnumKey can still store other values.
+type EnumKey struct {
+	store   KV
+	key     string
+	allowed map[string]bool
+}
+
+// NewEnumKey returns an EnumKey for key in store that accepts the values in
+// allowed.
+func NewEnumKey(store KV, key string, allowed []string) *EnumKey {
+	e := &EnumKey{store: store, key: key, allowed: make(map[string]bool, len(allowed))}
+	for _, v := range allowed {
+		e.allowed[v] = true
+	}
+	return e
+}
+
+// Get returns the key's value. ErrInvalidEnum is returned if the stored value
+// is not allowed.
+func (e *EnumKey) Get() (string, error) {
+	value, err := e.store.Get(e.key)
+	if err != nil {
+		return "", err
+	}
+	if !e.allowed[string(value)] {
+		return "", fmt.Errorf("%w: %q stored at %q", ErrInvalidEnum, value, e.key)
+	}
+	return string(value), nil
+}
+
+// Set stores v, or returns ErrInvalidEnum without writing if v is not
+// allowed.
+func (e *EnumKey) Set(v string) error {
+	if !e.allowed[v] {
+		return fmt.Errorf("%w: %q for %q", ErrInvalidEnum, v, e.key)
+	}
+	return e.store.Set(e.key, []byte(v))
+}
@@ -0,0 +1,26 @@
+package key_value
+
+import (
+	"os"
+	"strings"
+)
+
+// LoadEnv stores every environment variable whose name starts with prefix as
+// a key named by the rest of the variable's name, and returns how many it
+// stored. With prefix "CONFIG_", CONFIG_API_URL=https://example.com is stored
+// at "API_URL". Existing keys are overwritten; variables without the prefix,
+// and keys not named by a variable, are left alone.
+func LoadEnv(store KV, prefix string) (int, error) {
+	loaded := 0
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		if err := store.Set(strings.TrimPrefix(name, prefix), []byte(value)); err != nil {
+			return loaded, err
+		}
+		loaded++
+	}
+	return loaded, nil
+}
@@ -0,0 +1,376 @@
+package key_value
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// The envelope written by WithEnvelope is laid out as:
+//
+//	offset  size  field
+//	0       4     magic "\x00env"
+//	4       1     format version, currently 1
+//	5       1     flags
+//	6       8     expiry, big-endian Unix nanoseconds, if envelopeExpires
+//	              write time, big-endian Unix nanoseconds, if envelopeWritten
+//	        4     CRC-32 (IEEE) of the value, big-endian, if envelopeChecksum
+//	        …     payload
+//
+// The optional fields appear in the order listed, each only when its flag
+// is set. The payload is the value, gzip-compressed if envelopeCompressed,
+// and then, if envelopeEncrypted, sealed with AES-256-GCM as a 12-byte nonce
+// followed by the ciphertext and tag, with every byte before the payload as
+// additional data.
+const (
+	envelopeMagic   = "\x00env"
+	envelopeVersion = 1
+
+	envelopeCompressed = 1 << 0
+	envelopeEncrypted  = 1 << 1
+	envelopeExpires    = 1 << 2
+	envelopeWritten    = 1 << 3
+	envelopeChecksum   = 1 << 4
+	envelopeKnownFlags = 1<<5 - 1
+)
+
+// ErrNoEnvelope is returned by InspectEnvelope for a value that does not
+// start with an envelope.
+var ErrNoEnvelope = errors.New("value has no envelope")
+
+// EnvelopeOptions selects the transforms WithEnvelope applies to values.
+type EnvelopeOptions struct {
+	// Compress gzip-compresses values.
+	Compress bool
+	// Key, if not nil, encrypts values with AES-256-GCM under it.
+	Key *[32]byte
+	// Checksum records a CRC-32 of each value, checked on every read so
+	// that corrupted values are reported rather than returned.
+	Checksum bool
+	// TTL, if positive, makes values expire that long after being written.
+	TTL time.Duration
+	// Timestamp records when each value was written.
+	Timestamp bool
+}
+
+// Envelope describes an enveloped value, as reported by InspectEnvelope.
+type Envelope struct {
+	Version    uint8
+	Compressed bool
+	Encrypted  bool
+	// Expires is the value's expiry, or zero if it does not expire.
+	Expires time.Time
+	// Written is when the value was written, or zero if not recorded.
+	Written time.Time
+	// Checksum is the recorded CRC-32 of the value, if HasChecksum.
+	Checksum    uint32
+	HasChecksum bool
+	// Payload is the value as stored, after compression and encryption.
+	Payload []byte
+}
+
+// WithEnvelope returns store wrapped so that values are written in a single
+// self-describing envelope recording which of the transforms of opts were
+// applied, so compression, encryption, checksums, expiry and write times can
+// be combined on one value and read back in the right order. Get undoes the
+// transforms a value's envelope records, whatever opts are now, needing only
+// the key for encrypted values; values without an envelope are returned
+// unchanged, so a store can be switched over gradually. An expired value is
+// deleted when read and reported as ErrorNoSuchKey, and Exists reads the
+// value to honour expiry. Other operations pass straight through to store.
+//
+// The envelope is independent of the headers written by SetWithTTL and
+// WithSoftDelete, which should not be combined with it on the same keys.
+func WithEnvelope(store KV, opts EnvelopeOptions) KV {
+	return &envelope{KV: store, opts: opts}
+}
+
+type envelope struct {
+	KV
+	opts EnvelopeOptions
+}
+
+func (e *envelope) unwrap() KV {
+	return e.KV
+}
+
+func (e *envelope) Set(key string, value []byte) error {
+	raw, err := sealEnvelope(value, e.opts)
+	if err != nil {
+		return err
+	}
+	return e.KV.Set(key, raw)
+}
+
+func (e *envelope) Get(key string) ([]byte, error) {
+	raw, err := e.KV.Get(key)
+	if err != nil {
+		return raw, err
+	}
+	env, err := InspectEnvelope(raw)
+	if errors.Is(err, ErrNoEnvelope) {
+		return raw, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("value at %q: %w", key, err)
+	}
+	if !env.Expires.IsZero() && !time.Now().Before(env.Expires) {
+		if err := e.KV.Delete(key); err != nil && !errors.Is(err, ErrorNoSuchKey) {
+			return nil, err
+		}
+		return []byte{}, ErrorNoSuchKey
+	}
+	value, err := openEnvelope(raw, env, e.opts.Key)
+	if err != nil {
+		return nil, fmt.Errorf("value at %q: %w", key, err)
+	}
+	return value, nil
+}
+
+func (e *envelope) Exists(key string) (bool, error) {
+	_, err := e.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func sealEnvelope(value []byte, opts EnvelopeOptions) ([]byte, error) {
+	var expires, written time.Time
+	now := time.Now()
+	if opts.TTL > 0 {
+		expires = now.Add(opts.TTL)
+	}
+	if opts.Timestamp {
+		written = now
+	}
+	return sealValue(value, opts.Compress, opts.Key, opts.Checksum, expires, written)
+}
+
+// sealValue writes value in an envelope with the given transforms; a zero
+// expires or written time is not recorded.
+func sealValue(value []byte, compress bool, key *[32]byte, checksum bool, expires, written time.Time) ([]byte, error) {
+	var flags byte
+	header := []byte(envelopeMagic + "\x01\x00")
+	if compress {
+		flags |= envelopeCompressed
+	}
+	if key != nil {
+		flags |= envelopeEncrypted
+	}
+	if !expires.IsZero() {
+		flags |= envelopeExpires
+		header = appendUint64(header, uint64(expires.UnixNano()))
+	}
+	if !written.IsZero() {
+		flags |= envelopeWritten
+		header = appendUint64(header, uint64(written.UnixNano()))
+	}
+	if checksum {
+		flags |= envelopeChecksum
+		var sum [4]byte
+		binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(value))
+		header = append(header, sum[:]...)
+	}
+	header[len(envelopeMagic)+1] = flags
+
+	payload := value
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(value); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+	}
+	if key == nil {
+		return append(header, payload...), nil
+	}
+
+	aead, err := envelopeCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	raw := append(header, nonce...)
+	return aead.Seal(raw, nonce, payload, header), nil
+}
+
+func openEnvelope(raw []byte, env Envelope, key *[32]byte) ([]byte, error) {
+	value := env.Payload
+	if env.Encrypted {
+		if key == nil {
+			return nil, errors.New("value is encrypted and no key was given")
+		}
+		aead, err := envelopeCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(value) < aead.NonceSize() {
+			return nil, errors.New("encrypted payload is truncated")
+		}
+		header := raw[:len(raw)-len(env.Payload)]
+		nonce, sealed := value[:aead.NonceSize()], value[aead.NonceSize():]
+		if value, err = aead.Open(nil, nonce, sealed, header); err != nil {
+			return nil, err
+		}
+	}
+	if env.Compressed {
+		zr, err := gzip.NewReader(bytes.NewReader(value))
+		if err != nil {
+			return nil, err
+		}
+		if value, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	if env.HasChecksum && crc32.ChecksumIEEE(value) != env.Checksum {
+		return nil, errors.New("checksum mismatch")
+	}
+	return value, nil
+}
+
+// appendUint64 is binary.BigEndian.AppendUint64, which needs Go 1.19.
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func envelopeCipher(key *[32]byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// InspectEnvelope decodes the envelope header of a raw value, as stored by
+// WithEnvelope, for debugging; the payload is not decrypted or decompressed.
+// ErrNoEnvelope is returned if raw does not start with an envelope, and an
+// error if the envelope is truncated or of an unknown version.
+func InspectEnvelope(raw []byte) (Envelope, error) {
+	if len(raw) < len(envelopeMagic)+2 || string(raw[:len(envelopeMagic)]) != envelopeMagic {
+		return Envelope{}, ErrNoEnvelope
+	}
+	env := Envelope{Version: raw[len(envelopeMagic)]}
+	flags := raw[len(envelopeMagic)+1]
+	if env.Version != envelopeVersion || flags&^envelopeKnownFlags != 0 {
+		return Envelope{}, fmt.Errorf("unsupported envelope version %d with flags %#x", env.Version, flags)
+	}
+	env.Compressed = flags&envelopeCompressed != 0
+	env.Encrypted = flags&envelopeEncrypted != 0
+
+	rest := raw[len(envelopeMagic)+2:]
+	readTime := func() (time.Time, bool) {
+		if len(rest) < 8 {
+			return time.Time{}, false
+		}
+		t := time.Unix(0, int64(binary.BigEndian.Uint64(rest)))
+		rest = rest[8:]
+		return t, true
+	}
+	var ok bool
+	if flags&envelopeExpires != 0 {
+		if env.Expires, ok = readTime(); !ok {
+			return Envelope{}, errors.New("envelope is truncated")
+		}
+	}
+	if flags&envelopeWritten != 0 {
+		if env.Written, ok = readTime(); !ok {
+			return Envelope{}, errors.New("envelope is truncated")
+		}
+	}
+	if flags&envelopeChecksum != 0 {
+		if len(rest) < 4 {
+			return Envelope{}, errors.New("envelope is truncated")
+		}
+		env.Checksum, env.HasChecksum = binary.BigEndian.Uint32(rest), true
+		rest = rest[4:]
+	}
+	env.Payload = rest
+	return env, nil
+}
+
+// EncryptInPlace encrypts every value of store that is not yet encrypted
+// under key, in the envelope WithEnvelope reads, and returns how many values
+// it encrypted; it is the upgrade path for a store that a component starts
+// to read through WithEnvelope with EnvelopeOptions.Key set. Plain values
+// are wrapped in a new envelope, and enveloped ones are sealed again keeping
+// their compression, checksum, expiry and write time. Encrypted values are
+// skipped, as are nils stored by SetNil and values carrying the headers of
+// SetWithTTL or WithSoftDelete, which those helpers would no longer recognize
+// once encrypted, so an interrupted migration is resumed by running it again.
+//
+// Every value is read and rewritten with separate host calls, so a value
+// written by another instance in between can be overwritten with the
+// encrypted old value. EncryptInPlace does not manage key: losing it loses
+// every encrypted value, and it must be kept outside the store it protects.
+func EncryptInPlace(store KV, key [32]byte) (int, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	encrypted := 0
+	for _, k := range keys {
+		ok, err := encryptValue(store, k, &key)
+		if err != nil {
+			return encrypted, fmt.Errorf("encrypting %q: %w", k, err)
+		}
+		if ok {
+			encrypted++
+		}
+	}
+	return encrypted, nil
+}
+
+func encryptValue(store KV, k string, key *[32]byte) (bool, error) {
+	defer lockKey(store, k)()
+
+	raw, ok, err := GetIfExists(store, k)
+	if err != nil || !ok || string(raw) == nilValue {
+		return false, err
+	}
+	if _, _, ok := splitTTLHeader(raw); ok {
+		return false, nil
+	}
+	if _, _, ok := splitTombstone(raw); ok {
+		return false, nil
+	}
+
+	var sealed []byte
+	env, err := InspectEnvelope(raw)
+	switch {
+	case errors.Is(err, ErrNoEnvelope):
+		sealed, err = sealValue(raw, false, key, false, time.Time{}, time.Time{})
+	case err != nil:
+		return false, err
+	case env.Encrypted:
+		return false, nil
+	default:
+		var value []byte
+		if value, err = openEnvelope(raw, env, nil); err != nil {
+			return false, err
+		}
+		sealed, err = sealValue(value, env.Compressed, key, env.HasChecksum, env.Expires, env.Written)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, store.Set(k, sealed)
+}
@@ -0,0 +1,91 @@
+package key_value_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestEnvelope(t *testing.T) {
+	base := kvtest.NewMemStore()
+	key := &[32]byte{1, 2, 3}
+	store := key_value.WithEnvelope(base, key_value.EnvelopeOptions{
+		Compress:  true,
+		Key:       key,
+		Checksum:  true,
+		TTL:       time.Hour,
+		Timestamp: true,
+	})
+	kvtest.RoundTripTest(t, store)
+
+	value := bytes.Repeat([]byte("secret "), 100)
+	if err := store.Set("k", value); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base.Get("k")
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Fatal("stored value is not encrypted")
+	}
+	env, err := key_value.InspectEnvelope(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !env.Compressed || !env.Encrypted || !env.HasChecksum || env.Expires.IsZero() || env.Written.IsZero() {
+		t.Errorf("InspectEnvelope = %+v, want every transform recorded", env)
+	}
+
+	// Reading needs only the key, whatever the options now are.
+	reader := key_value.WithEnvelope(base, key_value.EnvelopeOptions{Key: key})
+	if got, err := reader.Get("k"); err != nil || !bytes.Equal(got, value) {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	base.Set("plain", []byte("plain"))
+	if got, err := reader.Get("plain"); err != nil || string(got) != "plain" {
+		t.Fatalf("Get of a value without an envelope = %q, %v", got, err)
+	}
+	if _, err := key_value.InspectEnvelope([]byte("plain")); !errors.Is(err, key_value.ErrNoEnvelope) {
+		t.Fatalf("InspectEnvelope of a plain value = %v, want ErrNoEnvelope", err)
+	}
+
+	expiring := key_value.WithEnvelope(base, key_value.EnvelopeOptions{TTL: time.Nanosecond})
+	expiring.Set("gone", []byte("x"))
+	time.Sleep(time.Millisecond)
+	if _, err := expiring.Get("gone"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Fatalf("Get of an expired value = %v, want ErrorNoSuchKey", err)
+	}
+}
+
+func TestEncryptInPlace(t *testing.T) {
+	base := kvtest.NewMemStore()
+	key := [32]byte{4, 5, 6}
+	base.Set("plain", []byte("plain value"))
+	key_value.WithEnvelope(base, key_value.EnvelopeOptions{Compress: true, Timestamp: true}).Set("compressed", []byte("compressed value"))
+	key_value.SetNil(base, "nil")
+
+	n, err := key_value.EncryptInPlace(base, key)
+	if err != nil || n != 2 {
+		t.Fatalf("EncryptInPlace = %d, %v, want 2", n, err)
+	}
+	if n, err := key_value.EncryptInPlace(base, key); err != nil || n != 0 {
+		t.Fatalf("second EncryptInPlace = %d, %v, want 0", n, err)
+	}
+
+	reader := key_value.WithEnvelope(base, key_value.EnvelopeOptions{Key: &key})
+	for k, want := range map[string]string{"plain": "plain value", "compressed": "compressed value"} {
+		if got, err := reader.Get(k); err != nil || string(got) != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", k, got, err, want)
+		}
+	}
+	raw, _ := base.Get("compressed")
+	if env, err := key_value.InspectEnvelope(raw); err != nil || !env.Encrypted || !env.Compressed || env.Written.IsZero() {
+		t.Errorf("re-sealed envelope = %+v, %v, want it encrypted and otherwise unchanged", env, err)
+	}
+	if ok, err := key_value.IsNil(base, "nil"); err != nil || !ok {
+		t.Errorf("IsNil after EncryptInPlace = %v, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,113 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is the error returned when the host fails a key-value operation.
+// Use errors.Is with the Error* values to check for a particular kind.
+type Error struct {
+	kind   uint8
+	detail string
+}
+
+// The kinds of error the host can report.
+var (
+	ErrorStoreTableFull = &Error{kind: errorKindStoreTableFull}
+	ErrorNoSuchStore    = &Error{kind: errorKindNoSuchStore}
+	ErrorAccessDenied   = &Error{kind: errorKindAccessDenied}
+	ErrorInvalidStore   = &Error{kind: errorKindInvalidStore}
+	ErrorNoSuchKey      = &Error{kind: errorKindNoSuchKey}
+	ErrorIo             = &Error{kind: errorKindIo}
+)
+
+// ErrKeyTooLarge and ErrValueTooLarge are returned by stores that enforce a
+// size limit on keys or values, such as kvtest.MemStore configured with
+// kvtest.WithLimits. The host itself reports an oversized key or value as an
+// ErrorIo from the backend.
+var (
+	ErrKeyTooLarge   = errors.New("key too large")
+	ErrValueTooLarge = errors.New("value too large")
+)
+
+func (e *Error) Error() string {
+	switch e.kind {
+	case errorKindStoreTableFull:
+		return "store table full"
+	case errorKindNoSuchStore:
+		return "no such store"
+	case errorKindAccessDenied:
+		return "access denied"
+	case errorKindInvalidStore:
+		return "invalid store"
+	case errorKindNoSuchKey:
+		return "no such key"
+	case errorKindIo:
+		return fmt.Sprintf("io error: %s", e.detail)
+	default:
+		return fmt.Sprintf("unrecognized error: %v", e.kind)
+	}
+}
+
+// Unknown reports whether the host returned a kind of error this version of
+// the SDK does not recognize, as can happen when running on a newer host.
+func (e *Error) Unknown() bool {
+	return e.kind > errorKindIo
+}
+
+// Tag returns the raw variant tag the host used for the error. It allows an
+// Unknown error to be told apart from another, and logged. The payload of an
+// unknown variant is not available, because the generated bindings only
+// decode the payloads of the variants they know.
+func (e *Error) Tag() uint8 {
+	return e.kind
+}
+
+// Detail returns the message the host gave with the error. Only io errors
+// carry one; for every other kind Detail returns "".
+func (e *Error) Detail() string {
+	return e.detail
+}
+
+// Is reports whether target is an *Error of the same kind, ignoring any
+// detail carried by an io error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.kind == e.kind
+}
+
+// OpError is the error returned by the functions operating on a Store. It
+// records the operation, the name of the store and the key involved, and
+// wraps the *Error from the host, so errors.Is matches the Error* values and
+// errors.As retrieves the *Error.
+type OpError struct {
+	// Op is the operation: "open", "get", "set", "delete", "exists" or
+	// "get-keys".
+	Op string
+	// Store is the name the store was opened with, or "#" and the handle if
+	// the store is not open.
+	Store string
+	// Key is the key operated on. It is empty for open and get-keys.
+	Key string
+	Err error
+}
+
+func (e *OpError) Error() string {
+	if e.Op == "open" || e.Op == "get-keys" {
+		return fmt.Sprintf("kv[%s] %s: %v", e.Store, e.Op, e.Err)
+	}
+	return fmt.Sprintf("kv[%s] %s %q: %v", e.Store, e.Op, e.Key, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+func opError(op string, store Store, key string, err error) error {
+	name, ok := storeNames.lookup(store)
+	if !ok {
+		name = fmt.Sprintf("#%d", uint32(store))
+	}
+	return &OpError{Op: op, Store: name, Key: key, Err: err}
+}
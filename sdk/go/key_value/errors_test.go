@@ -0,0 +1,28 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+)
+
+func TestOpError(t *testing.T) {
+	var err error = &key_value.OpError{Op: "get", Store: "default", Key: "user:1", Err: key_value.ErrorNoSuchKey}
+
+	if got, want := err.Error(), `kv[default] get "user:1": no such key`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Errorf("errors.Is(%v, ErrorNoSuchKey) = false", err)
+	}
+	var kvErr *key_value.Error
+	if !errors.As(err, &kvErr) || kvErr != key_value.ErrorNoSuchKey {
+		t.Errorf("errors.As(%v) = %v, want ErrorNoSuchKey", err, kvErr)
+	}
+
+	err = &key_value.OpError{Op: "get-keys", Store: "default", Err: key_value.ErrorInvalidStore}
+	if got, want := err.Error(), "kv[default] get-keys: invalid store"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
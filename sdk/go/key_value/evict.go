@@ -0,0 +1,173 @@
+package key_value
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AccessTimePrefix is prepended to a key to form the key WithAccessTracking
+// records its last access time under.
+const AccessTimePrefix = "atime:"
+
+// WithEvictOnFull returns store wrapped so that a Set failing because the
+// store is full calls evict to free space and then retries the Set once. The
+// host has no dedicated error for a full store, so a failure counts as full
+// if it is ErrorStoreTableFull or an io error whose message mentions the
+// store being full or out of space or quota. Other operations pass straight
+// through to store.
+//
+// Eviction is best-effort: evict is called with the wrapped store, and if it
+// cannot free enough space the retried Set's error is returned.
+func WithEvictOnFull(store KV, evict func(store KV) error) KV {
+	return &evictOnFull{KV: store, evict: evict}
+}
+
+type evictOnFull struct {
+	KV
+	evict func(KV) error
+}
+
+func (e *evictOnFull) unwrap() KV {
+	return e.KV
+}
+
+func (e *evictOnFull) Set(key string, value []byte) error {
+	err := e.KV.Set(key, value)
+	if !isFullError(err) {
+		return err
+	}
+	if err := e.evict(e.KV); err != nil {
+		return err
+	}
+	return e.KV.Set(key, value)
+}
+
+func isFullError(err error) bool {
+	if errors.Is(err, ErrorStoreTableFull) {
+		return true
+	}
+	var kvErr *Error
+	if !errors.As(err, &kvErr) || kvErr.kind != errorKindIo {
+		return false
+	}
+	detail := strings.ToLower(kvErr.detail)
+	for _, word := range []string{"full", "space", "quota", "capacity"} {
+		if strings.Contains(detail, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAccessTracking returns store wrapped so that every successful Get or
+// Set of a key also records the time under AccessTimePrefix+key, for
+// EvictLRU to choose which keys to evict. Deleting a key deletes its record.
+//
+// Tracking costs an extra write on every read and write, and a record of
+// about 8 bytes plus the key per key, so it is opt-in: read-heavy or
+// read-only workloads that do not need it should use the store unwrapped.
+// Records are only written through the returned KV: keys accessed through
+// other handles look older than they are. Recording is best-effort: a Get or
+// Set whose record cannot be written, such as because the store is full,
+// still succeeds.
+func WithAccessTracking(store KV) KV {
+	return &accessTracking{KV: store}
+}
+
+type accessTracking struct {
+	KV
+}
+
+func (a *accessTracking) unwrap() KV {
+	return a.KV
+}
+
+func (a *accessTracking) touch(key string) {
+	SetInt64(a.KV, AccessTimePrefix+key, time.Now().UnixNano())
+}
+
+func (a *accessTracking) Get(key string) ([]byte, error) {
+	value, err := a.KV.Get(key)
+	if err == nil && !strings.HasPrefix(key, AccessTimePrefix) {
+		a.touch(key)
+	}
+	return value, err
+}
+
+func (a *accessTracking) Set(key string, value []byte) error {
+	err := a.KV.Set(key, value)
+	if err == nil && !strings.HasPrefix(key, AccessTimePrefix) {
+		a.touch(key)
+	}
+	return err
+}
+
+func (a *accessTracking) Delete(key string) error {
+	if err := a.KV.Delete(key); err != nil || strings.HasPrefix(key, AccessTimePrefix) {
+		return err
+	}
+	return a.KV.Delete(AccessTimePrefix + key)
+}
+
+// LastAccess returns when key was last read or written through a KV returned
+// by WithAccessTracking, or ErrorNoSuchKey if it has no record.
+func LastAccess(store KV, key string) (time.Time, error) {
+	if a, ok := findOption[*accessTracking](store); ok {
+		store = a.KV
+	}
+	nanos, err := GetInt64(store, AccessTimePrefix+key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// EvictLRU returns an eviction function for WithEvictOnFull that deletes the
+// n least recently accessed keys, as recorded by WithAccessTracking. Keys
+// with no record count as the least recently accessed. It lists every key
+// and reads every record, and records are only as fresh as the number of
+// instances writing them allows, so the eviction order is approximate.
+// EvictLRU panics if n is negative.
+func EvictLRU(n int) func(store KV) error {
+	if n < 0 {
+		panic("key_value: EvictLRU called with n < 0")
+	}
+	return func(store KV) error {
+		keys, err := store.GetKeys()
+		if err != nil {
+			return err
+		}
+
+		type candidate struct {
+			key      string
+			accessed int64
+		}
+		var candidates []candidate
+		for _, key := range keys {
+			if strings.HasPrefix(key, AccessTimePrefix) {
+				continue
+			}
+			accessed, err := GetInt64(store, AccessTimePrefix+key)
+			if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+				return err
+			}
+			candidates = append(candidates, candidate{key, accessed})
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].accessed < candidates[j].accessed })
+
+		if n < len(candidates) {
+			candidates = candidates[:n]
+		}
+		for _, c := range candidates {
+			if err := store.Delete(c.key); err != nil {
+				return err
+			}
+			if err := store.Delete(AccessTimePrefix + c.key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
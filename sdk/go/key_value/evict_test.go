@@ -0,0 +1,66 @@
+package key_value_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestAccessTracking(t *testing.T) {
+	backing := kvtest.NewMemStore(kvtest.WithLimits(kvtest.Limits{MaxKeys: 3}))
+	store := key_value.WithAccessTracking(backing)
+
+	before := time.Now()
+	if err := store.Set("a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	accessed, err := key_value.LastAccess(store, "a")
+	if err != nil || accessed.Before(before) {
+		t.Errorf("LastAccess after Set = %v, %v, want after %v", accessed, err, before)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := store.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := key_value.LastAccess(store, "a"); err != nil || !again.After(accessed) {
+		t.Errorf("LastAccess after Get = %v, %v, want after %v", again, err, accessed)
+	}
+
+	// The store now holds a, its record and b, with no room for b's record.
+	if err := store.Set("b", []byte("2")); err != nil {
+		t.Errorf("Set with no room for the access record: %v", err)
+	}
+	if _, err := store.Get("b"); err != nil {
+		t.Errorf("Get with no room for the access record: %v", err)
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := backing.Exists(key_value.AccessTimePrefix + "a"); ok {
+		t.Error("Delete left the access record behind")
+	}
+}
+
+func TestEvictLRU(t *testing.T) {
+	backing := kvtest.NewMemStore(kvtest.WithLimits(kvtest.Limits{MaxKeys: 4}))
+	store := key_value.WithEvictOnFull(key_value.WithAccessTracking(backing), key_value.EvictLRU(1))
+
+	store.Set("a", []byte("1"))
+	store.Set("b", []byte("2"))
+	time.Sleep(time.Millisecond)
+	if _, err := store.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("c", []byte("3")); err != nil {
+		t.Fatalf("Set on a full store: %v", err)
+	}
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if ok, err := backing.Exists(key); err != nil || ok != want {
+			t.Errorf("Exists(%q) = %v, %v after eviction, want %v", key, ok, err, want)
+		}
+	}
+}
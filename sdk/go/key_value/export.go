@@ -0,0 +1,90 @@
+package key_value
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type exportRecord struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Export writes every entry of store to w as newline-delimited JSON, one
+// object of the form {"key": "…", "value": "<base64>"} per line, in key
+// order. Keys deleted during the export are skipped; the export is not a
+// consistent snapshot of a store that is being written to.
+func Export(store KV, w io.Writer) error {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(exportRecord{Key: key, Value: value}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Import reads an export written by Export from r and sets each entry in
+// store, overwriting existing keys, and returns how many entries it set. It
+// stops at the first malformed line or store error, leaving the entries set
+// so far in place.
+func Import(store KV, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	imported := 0
+	for {
+		var rec exportRecord
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, fmt.Errorf("entry %d: %w", imported+1, err)
+		}
+		if err := store.Set(rec.Key, rec.Value); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+}
+
+// ExportGzip writes the export of Export to w compressed with gzip, for
+// backing up a sizeable store over outbound HTTP or into another store. The
+// gzip stream is closed, writing its footer, before ExportGzip returns; w
+// itself is not closed.
+func ExportGzip(store KV, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := Export(store, zw); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportGzip reads an export written by ExportGzip from r, as Import does.
+func ImportGzip(store KV, r io.Reader) (int, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	return Import(store, zr)
+}
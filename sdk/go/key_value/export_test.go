@@ -0,0 +1,30 @@
+package key_value_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestExportGzip(t *testing.T) {
+	src := kvtest.NewMemStore()
+	src.Set("a", []byte("one"))
+	src.Set("b", []byte{0, 1, 2})
+	src.Set("empty", []byte{})
+
+	var buf bytes.Buffer
+	if err := key_value.ExportGzip(src, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := kvtest.NewMemStore()
+	n, err := key_value.ImportGzip(dst, &buf)
+	if err != nil || n != 3 {
+		t.Fatalf("ImportGzip = %d, %v, want 3", n, err)
+	}
+	if equal, err := key_value.Equal(src, dst); err != nil || !equal {
+		t.Fatalf("imported store differs from the exported one: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FlagPrefix is prepended to a flag name to form the key the flag is stored
+// under, so the flag "beta" is kept at the key "flag:beta". The value is the
+// single byte "1" when the flag is on and "0" when it is off; a flag whose key
+// does not exist is off. External tooling can toggle a flag by writing one of
+// those values to the key.
+const FlagPrefix = "flag:"
+
+// Flags provides feature flags backed by a key-value store.
+type Flags struct {
+	store KV
+}
+
+// NewFlags returns feature flags kept in store.
+func NewFlags(store KV) *Flags {
+	return &Flags{store: store}
+}
+
+// Enabled reports whether the named flag is on.
+func (f *Flags) Enabled(name string) (bool, error) {
+	value, err := f.store.Get(FlagPrefix + name)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return parseFlag(name, value)
+}
+
+// Set turns the named flag on or off.
+func (f *Flags) Set(name string, on bool) error {
+	value := []byte("0")
+	if on {
+		value = []byte("1")
+	}
+	return f.store.Set(FlagPrefix+name, value)
+}
+
+// All returns the state of every flag in the store, keyed by flag name.
+func (f *Flags) All() (map[string]bool, error) {
+	keys, err := keysWithPrefix(f.store, FlagPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]bool)
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, FlagPrefix)
+		value, err := f.store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if flags[name], err = parseFlag(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return flags, nil
+}
+
+func parseFlag(name string, value []byte) (bool, error) {
+	switch string(value) {
+	case "1":
+		return true, nil
+	case "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid value %q for flag %q", value, name)
+	}
+}
@@ -0,0 +1,63 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrExpired is returned by GetFreshJSON when the document's own expiry time
+// has passed.
+var ErrExpired = errors.New("value expired")
+
+// GetFreshJSON decodes the JSON document stored at key into v, unless the
+// time at expiryField, a path as accepted by GetJSONField, has passed, in
+// which case the key is deleted and ErrExpired is returned. It suits values
+// that carry their own expiry, such as an OAuth token with an "expires_at"
+// field, without a separate TTL layer.
+//
+// The expiry field may be a string in RFC 3339 format, with or without
+// fractional seconds, or a number of seconds since the Unix epoch. A missing
+// or null field returns ErrFieldNotFound without decoding into v.
+func GetFreshJSON(store KV, key, expiryField string, v interface{}) error {
+	value, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return err
+	}
+	field, err := lookupJSONPath(doc, expiryField)
+	if err != nil {
+		return err
+	}
+	expires, err := parseExpiry(field)
+	if err != nil {
+		return fmt.Errorf("field %q: %w", expiryField, err)
+	}
+	if !time.Now().Before(expires) {
+		if err := store.Delete(key); err != nil && !errors.Is(err, ErrorNoSuchKey) {
+			return err
+		}
+		return ErrExpired
+	}
+	return jsonCodecFor(store).Unmarshal(value, v)
+}
+
+func parseExpiry(field interface{}) (time.Time, error) {
+	switch f := field.(type) {
+	case nil:
+		return time.Time{}, ErrFieldNotFound
+	case string:
+		return time.Parse(time.RFC3339Nano, f)
+	case float64:
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	default:
+		return time.Time{}, fmt.Errorf("expiry is a %T, not a time", field)
+	}
+}
@@ -0,0 +1,197 @@
+package key_value
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS returns a read-only fs.FS presenting the keys of store as file paths and
+// their values as file contents, so code that reads from an fs.FS, such as
+// html/template or http.FileServer, can read straight from the store.
+//
+// Keys are split into directories at each "/": the key "static/css/site.css"
+// is the file site.css in the directory static/css. Directories exist only
+// while some key lies beneath them, and listing a directory lists every key
+// in the store. Keys that are not valid fs paths, such as ones with a leading
+// "/" or an empty element, are not visible. If a key is also a prefix
+// directory of other keys, as with "a" and "a/b", the file hides the
+// directory. Files report a zero modification time.
+func FS(store KV) fs.FS {
+	return kvFS{store: store}
+}
+
+type kvFS struct {
+	store KV
+}
+
+var (
+	_ fs.ReadFileFS = kvFS{}
+	_ fs.ReadDirFS  = kvFS{}
+)
+
+func (f kvFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name != "." {
+		data, err := f.readFile(name)
+		if err == nil {
+			return &kvFile{info: fileInfo{name: path.Base(name), size: int64(len(data))}, Reader: bytes.NewReader(data)}, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &kvDir{info: fileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+func (f kvFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	data, err := f.readFile(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+func (f kvFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name != "." {
+		if ok, err := f.store.Exists(name); err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		} else if ok {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+	}
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+func (f kvFS) readFile(name string) ([]byte, error) {
+	data, err := f.store.Get(name)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, fs.ErrNotExist
+	}
+	return data, err
+}
+
+// readDir lists the entries of the directory name, sorted by name. It returns
+// fs.ErrNotExist if no key lies beneath name.
+func (f kvFS) readDir(name string) ([]fs.DirEntry, error) {
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+	keys, err := keysWithPrefix(f.store, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	entries := []fs.DirEntry{}
+	for _, key := range keys {
+		if !fs.ValidPath(key) {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix)
+		elem, _, isDir := strings.Cut(rest, "/")
+		if seen[elem] {
+			continue
+		}
+		seen[elem] = true
+
+		info := fileInfo{name: elem, dir: isDir}
+		if !isDir {
+			if data, err := f.store.Get(key); err == nil {
+				info.size = int64(len(data))
+			} else if !errors.Is(err, ErrorNoSuchKey) {
+				return nil, err
+			}
+		}
+		entries = append(entries, info)
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, fs.ErrNotExist
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// fileInfo describes a file or directory, serving as both its fs.FileInfo
+// and its fs.DirEntry.
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i fileInfo) Name() string               { return i.name }
+func (i fileInfo) Size() int64                { return i.size }
+func (i fileInfo) ModTime() time.Time         { return time.Time{} }
+func (i fileInfo) IsDir() bool                { return i.dir }
+func (i fileInfo) Sys() interface{}           { return nil }
+func (i fileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i fileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// kvFile is an open file. The embedded reader also provides Seek and ReadAt,
+// which http.FileServer uses for range requests.
+type kvFile struct {
+	info fileInfo
+	*bytes.Reader
+}
+
+func (f *kvFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *kvFile) Close() error               { return nil }
+
+type kvDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *kvDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *kvDir) Close() error               { return nil }
+
+func (d *kvDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *kvDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
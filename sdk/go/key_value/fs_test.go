@@ -0,0 +1,27 @@
+package key_value_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestFS(t *testing.T) {
+	store := kvtest.NewMemStore()
+	for key, value := range map[string]string{
+		"index.html":          "<h1>hi</h1>",
+		"static/css/site.css": "body {}",
+		"static/js/app.js":    "main()",
+		"static/empty":        "",
+		"/not-a-path":         "hidden",
+	} {
+		store.Set(key, []byte(value))
+	}
+
+	fsys := key_value.FS(store)
+	if err := fstest.TestFS(fsys, "index.html", "static/css/site.css", "static/js/app.js", "static/empty"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,118 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handler returns an http.Handler exposing store as a small REST API, so
+// that a Spin HTTP component can serve a store in one line:
+//
+//	GET    /kv/       lists the keys as a JSON array of strings
+//	GET    /kv/{key}  returns the value as application/octet-stream
+//	PUT    /kv/{key}  sets the value to the request body
+//	DELETE /kv/{key}  deletes the key
+//
+// The key is the rest of the decoded path and may contain "/". PUT and
+// DELETE answer 204 No Content. ErrorNoSuchKey is answered with 404 Not
+// Found, ErrorAccessDenied with 403 Forbidden and any other error with 500,
+// whose detail is logged with the log package rather than sent; error
+// bodies hold only the status text. Paths outside /kv/ get 404 and other
+// methods 405.
+//
+// The handler does no authentication or authorization of its own: anyone who
+// can reach it can read and write the whole store. Mount it behind the
+// component's own guards.
+func Handler(store KV) http.Handler {
+	return &kvHandler{store: store}
+}
+
+type kvHandler struct {
+	store KV
+}
+
+func (h *kvHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := cutPrefix(r.URL.Path, "/kv/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if key == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		keys, err := h.store.GetKeys()
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(keys)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		value, err := h.store.Get(key)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(value)
+	case http.MethodPut:
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.store.Set(key, value); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := h.store.Delete(key); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrorNoSuchKey):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrorAccessDenied):
+		status = http.StatusForbidden
+	}
+	if status == http.StatusInternalServerError {
+		// The error names the store and key and carries the host's detail,
+		// which are for the component's logs rather than the client.
+		log.Printf("kv handler: %v", err)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// cutPrefix is strings.CutPrefix, which needs Go 1.20.
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
@@ -0,0 +1,63 @@
+package key_value_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestHandler(t *testing.T) {
+	h := key_value.Handler(kvtest.NewMemStore())
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := serve("GET", "/kv/a/b", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET of a missing key = %d, want 404", w.Code)
+	}
+	if w := serve("PUT", "/kv/a/b", "value"); w.Code != http.StatusNoContent {
+		t.Errorf("PUT = %d, want 204", w.Code)
+	}
+	if w := serve("GET", "/kv/a/b", ""); w.Code != http.StatusOK || w.Body.String() != "value" {
+		t.Errorf("GET = %d %q, want 200 \"value\"", w.Code, w.Body.String())
+	}
+	if w := serve("GET", "/kv/", ""); w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `["a/b"]` {
+		t.Errorf("list = %d %q", w.Code, w.Body.String())
+	}
+	if w := serve("DELETE", "/kv/a/b", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want 204", w.Code)
+	}
+	if w := serve("POST", "/kv/a/b", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", w.Code)
+	}
+}
+
+func TestHandlerHidesErrorDetail(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	store := kvtest.NewMemStore()
+	store.Close()
+	w := httptest.NewRecorder()
+	key_value.Handler(store).ServeHTTP(w, httptest.NewRequest("GET", "/kv/secret-key", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("GET on a closed store = %d, want 500", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("error body = %q, want the status text only", body)
+	}
+	if logged.Len() == 0 {
+		t.Error("the error was not logged")
+	}
+}
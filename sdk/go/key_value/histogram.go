@@ -0,0 +1,73 @@
+package key_value
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HistogramPrefix is prepended to the keys Histogram keeps its bucket
+// counters in.
+const HistogramPrefix = "hist:"
+
+// Histogram counts observations, such as request latencies or payload sizes,
+// in buckets, sharing the counts across every component instance using the
+// same store.
+//
+// The buckets are set by their upper bounds: an observation is counted in
+// the first bucket whose bound is at least the value, or in the "+Inf"
+// bucket above them all. Each bucket's count is kept at
+// HistogramPrefix+name+":"+bound, with the bound formatted as by
+// strconv.FormatFloat with format 'g', and counts only the observations in
+// that bucket, not those below it. Every instance observing a histogram must
+// use the same bounds.
+//
+// Counts are updated with Increment, which is not atomic on the host, so
+// concurrent observations can be undercounted.
+type Histogram struct {
+	store  KV
+	bounds []float64
+}
+
+// NewHistogram returns a Histogram with buckets bounded above by bounds,
+// keeping its counts in store.
+func NewHistogram(store KV, bounds []float64) *Histogram {
+	sorted := append([]float64{}, bounds...)
+	sort.Float64s(sorted)
+	return &Histogram{store: store, bounds: sorted}
+}
+
+// Observe counts value in its bucket of the histogram called name.
+func (h *Histogram) Observe(name string, value float64) error {
+	bucket := math.Inf(1)
+	for _, bound := range h.bounds {
+		if value <= bound {
+			bucket = bound
+			break
+		}
+	}
+	_, err := Increment(h.store, histogramKey(name, bucket), 1)
+	return err
+}
+
+// Snapshot returns the count of every bucket of the histogram called name,
+// keyed by the bucket's formatted upper bound. Buckets with no observations
+// have a count of zero.
+func (h *Histogram) Snapshot(name string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(h.bounds)+1)
+	for _, bound := range append(append([]float64{}, h.bounds...), math.Inf(1)) {
+		key := histogramKey(name, bound)
+		n, err := GetInt64(h.store, key)
+		if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+			return nil, err
+		}
+		counts[strings.TrimPrefix(key, HistogramPrefix+name+":")] = n
+	}
+	return counts, nil
+}
+
+func histogramKey(name string, bound float64) string {
+	return HistogramPrefix + name + ":" + strconv.FormatFloat(bound, 'g', -1, 64)
+}
@@ -0,0 +1,136 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HistoryPrefix is prepended to the keys WithHistory keeps earlier values in.
+// The n-th slot of a key's history is at HistoryPrefix+key+":"+n, and the
+// number of values ever recorded at HistoryPrefix+key+":count".
+const HistoryPrefix = "__hist:"
+
+// WithHistory returns store wrapped so that every Set or Delete of a key
+// through it first records the key's previous value, for History to list and
+// Restore to bring back. Up to max values are kept per key, in a ring: once a
+// key has max recorded values, each new one overwrites the oldest. GetKeys
+// through the returned KV leaves out the history keys.
+//
+// Each overwrite costs an extra read and two extra writes, and a key can take
+// up to max+1 times the space of its value. Only writes through the returned
+// KV are recorded, and recording is not atomic with the write it precedes.
+// Set and Delete take no key lock of their own, since the read-modify-write
+// helpers already hold it when they write through the returned KV; two plain
+// Sets of the same key racing each other can record the same previous value.
+func WithHistory(store KV, max int) KV {
+	if max < 1 {
+		panic("key_value: WithHistory called with max < 1")
+	}
+	return &history{KV: store, max: max}
+}
+
+type history struct {
+	KV
+	max int
+}
+
+func (h *history) unwrap() KV {
+	return h.KV
+}
+
+// record saves the current value of key, if any, into its history.
+func (h *history) record(key string) error {
+	value, err := h.KV.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	count, err := GetInt64(h.KV, historyCountKey(key))
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return err
+	}
+	if err := h.KV.Set(historySlotKey(key, count%int64(h.max)), value); err != nil {
+		return err
+	}
+	return SetInt64(h.KV, historyCountKey(key), count+1)
+}
+
+func (h *history) Set(key string, value []byte) error {
+	if err := h.record(key); err != nil {
+		return err
+	}
+	return h.KV.Set(key, value)
+}
+
+func (h *history) Delete(key string) error {
+	if err := h.record(key); err != nil {
+		return err
+	}
+	return h.KV.Delete(key)
+}
+
+func (h *history) GetKeys() ([]string, error) {
+	keys, err := h.KV.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+	filtered := keys[:0]
+	for _, key := range keys {
+		if !strings.HasPrefix(key, HistoryPrefix) {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered, nil
+}
+
+// History returns the recorded earlier values of key, most recent first. store
+// must have been wrapped by WithHistory.
+func History(store KV, key string) ([][]byte, error) {
+	h, ok := findOption[*history](store)
+	if !ok {
+		return nil, errors.New("store does not keep history")
+	}
+	count, err := GetInt64(h.KV, historyCountKey(key))
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]byte
+	for i := count - 1; i >= 0 && i >= count-int64(h.max); i-- {
+		value, err := h.KV.Get(historySlotKey(key, i%int64(h.max)))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Restore sets key back to the n-th value History returns for it, 0 being
+// the most recent. The value being replaced is recorded in the history like
+// any other.
+func Restore(store KV, key string, n int) error {
+	values, err := History(store, key)
+	if err != nil {
+		return err
+	}
+	if n < 0 || n >= len(values) {
+		return fmt.Errorf("no history entry %d for %q", n, key)
+	}
+	return store.Set(key, values[n])
+}
+
+func historyCountKey(key string) string {
+	return HistoryPrefix + key + ":count"
+}
+
+func historySlotKey(key string, slot int64) string {
+	return HistoryPrefix + key + ":" + strconv.FormatInt(slot, 10)
+}
@@ -0,0 +1,45 @@
+package key_value_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestHistoryOverKeyLocking(t *testing.T) {
+	store := key_value.WithHistory(key_value.WithKeyLocking(kvtest.NewMemStore()), 3)
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if _, err := key_value.Increment(store, "n", 1); err != nil {
+				done <- err
+				return
+			}
+		}
+		_, err := key_value.SetDefaults(store, map[string][]byte{"d": []byte("x")})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Increment over WithHistory(WithKeyLocking(..)) deadlocked")
+	}
+
+	n, err := key_value.GetInt64(store, "n")
+	if err != nil || n != 3 {
+		t.Fatalf("GetInt64 = %d, %v, want 3", n, err)
+	}
+	history, err := key_value.History(store, "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Errorf("History recorded %d values, want 2", len(history))
+	}
+}
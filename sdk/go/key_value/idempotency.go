@@ -0,0 +1,70 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// IdempotencyPrefix is prepended to an idempotency key to form the key its
+// recorded result is stored under.
+const IdempotencyPrefix = "idempotency:"
+
+// Idempotency records the results of operations by idempotency key so that a
+// retried request, such as a POST resent by a client after a timeout, gets
+// the original result instead of repeating the operation.
+type Idempotency struct {
+	store KV
+}
+
+// NewIdempotency returns an Idempotency that records results in store.
+func NewIdempotency(store KV) *Idempotency {
+	return &Idempotency{store: store}
+}
+
+type idempotentResult struct {
+	Expires int64  `json:"expires"`
+	Result  []byte `json:"result"`
+}
+
+// Do runs fn and records its result under key for ttl, unless a result for
+// key is already recorded, in which case that result is returned without
+// running fn. The bool reports whether fn ran. If fn fails its error is
+// returned and nothing is recorded, so a retry runs fn again.
+//
+// Checking for a recorded result and recording a new one are separate host
+// calls, and the result is only recorded after fn returns. Two requests with
+// the same key arriving at different instances close together can therefore
+// both run fn. Within one process, Do calls on a store wrapped by
+// WithKeyLocking do not overlap for the same key.
+func (i *Idempotency) Do(key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, bool, error) {
+	storeKey := IdempotencyPrefix + key
+	defer lockKey(i.store, storeKey)()
+
+	raw, err := i.store.Get(storeKey)
+	switch {
+	case err == nil:
+		var recorded idempotentResult
+		if err := json.Unmarshal(raw, &recorded); err != nil {
+			return nil, false, err
+		}
+		if time.Now().UnixNano() < recorded.Expires {
+			return recorded.Result, false, nil
+		}
+	case !errors.Is(err, ErrorNoSuchKey):
+		return nil, false, err
+	}
+
+	result, err := fn()
+	if err != nil {
+		return nil, true, err
+	}
+	raw, err = json.Marshal(idempotentResult{Expires: time.Now().Add(ttl).UnixNano(), Result: result})
+	if err != nil {
+		return nil, true, err
+	}
+	if err := i.store.Set(storeKey, raw); err != nil {
+		return result, true, err
+	}
+	return result, true, nil
+}
@@ -0,0 +1,164 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Indexed stores records under RecordPrefix and index entries under
+// IndexPrefix.
+const (
+	RecordPrefix = "record:"
+	IndexPrefix  = "idx:"
+)
+
+// ErrDuplicateIndex is returned by Indexed.Put and SetUnique when another
+// record or key already has the same unique value.
+var ErrDuplicateIndex = errors.New("duplicate index value")
+
+// Indexed stores JSON records by id and keeps unique secondary indexes on
+// some of their top-level fields, so records can be looked up by, say, email
+// address as well as by id. A record is stored at RecordPrefix+id, and each
+// indexed field value at IndexPrefix+field+":"+value, holding the id of the
+// record with that value. String fields are indexed by their value and other
+// fields by their JSON encoding; records without a field, or with it null,
+// have no entry for it.
+//
+// A record and its index entries are separate keys updated one at a time, so
+// a failure or a concurrent update part way through can leave an index entry
+// pointing at a record that no longer has that value, or a record missing
+// from an index. Updates of the same id within this process do not overlap
+// if the store was wrapped by WithKeyLocking.
+type Indexed struct {
+	store  KV
+	fields []string
+}
+
+// NewIndexed returns an Indexed keeping records in store and indexing them by
+// fields.
+func NewIndexed(store KV, fields ...string) *Indexed {
+	return &Indexed{store: store, fields: fields}
+}
+
+// Put stores record, which must encode as a JSON object, under id, replacing
+// any previous record and updating the indexes to match. It returns an error
+// matching ErrDuplicateIndex, without storing anything, if another record
+// already has one of the record's indexed values.
+func (x *Indexed) Put(id string, record interface{}) error {
+	defer lockKey(x.store, RecordPrefix+id)()
+
+	data, err := jsonCodecFor(x.store).Marshal(record)
+	if err != nil {
+		return err
+	}
+	values, err := x.indexValues(data)
+	if err != nil {
+		return err
+	}
+	old, err := x.currentValues(id)
+	if err != nil {
+		return err
+	}
+
+	for field, value := range values {
+		owner, err := x.store.Get(indexKey(field, value))
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if string(owner) != id {
+			return fmt.Errorf("%w: %s %q is used by %q", ErrDuplicateIndex, field, value, owner)
+		}
+	}
+	for field, value := range values {
+		if old[field] == value {
+			continue
+		}
+		if err := x.store.Set(indexKey(field, value), []byte(id)); err != nil {
+			return err
+		}
+	}
+	if err := x.store.Set(RecordPrefix+id, data); err != nil {
+		return err
+	}
+	for field, value := range old {
+		if values[field] != value {
+			if err := x.store.Delete(indexKey(field, value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get decodes the record stored under id into v.
+func (x *Indexed) Get(id string, v interface{}) error {
+	return GetJSON(x.store, RecordPrefix+id, v)
+}
+
+// GetByIndex returns the id of the record whose field has value. It returns
+// ErrorNoSuchKey if no record has it.
+func (x *Indexed) GetByIndex(field, value string) (string, error) {
+	id, err := x.store.Get(indexKey(field, value))
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// Delete deletes the record stored under id and its index entries.
+func (x *Indexed) Delete(id string) error {
+	defer lockKey(x.store, RecordPrefix+id)()
+
+	old, err := x.currentValues(id)
+	if err != nil {
+		return err
+	}
+	for field, value := range old {
+		if err := x.store.Delete(indexKey(field, value)); err != nil {
+			return err
+		}
+	}
+	return x.store.Delete(RecordPrefix + id)
+}
+
+// currentValues returns the indexed values of the record stored under id, or
+// none if there is no such record.
+func (x *Indexed) currentValues(id string) (map[string]string, error) {
+	data, err := x.store.Get(RecordPrefix + id)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return x.indexValues(data)
+}
+
+func (x *Indexed) indexValues(data []byte) (map[string]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("indexed record is not a JSON object: %w", err)
+	}
+	values := make(map[string]string)
+	for _, field := range x.fields {
+		raw, ok := fields[field]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			values[field] = s
+		} else {
+			values[field] = string(raw)
+		}
+	}
+	return values, nil
+}
+
+func indexKey(field, value string) string {
+	return IndexPrefix + field + ":" + value
+}
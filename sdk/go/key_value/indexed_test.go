@@ -0,0 +1,48 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+type user struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestIndexed(t *testing.T) {
+	users := key_value.NewIndexed(kvtest.NewMemStore(), "email")
+
+	if err := users.Put("1", user{Name: "Ada", Email: "ada@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := users.GetByIndex("email", "ada@example.com"); err != nil || id != "1" {
+		t.Fatalf("GetByIndex = %q, %v, want 1", id, err)
+	}
+
+	err := users.Put("2", user{Name: "Imposter", Email: "ada@example.com"})
+	if !errors.Is(err, key_value.ErrDuplicateIndex) {
+		t.Fatalf("Put of a duplicate email: got error %v, want %v", err, key_value.ErrDuplicateIndex)
+	}
+
+	if err := users.Put("1", user{Name: "Ada", Email: "ada@example.org"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetByIndex("email", "ada@example.com"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Fatalf("GetByIndex of the old email: got error %v, want %v", err, key_value.ErrorNoSuchKey)
+	}
+	var got user
+	if err := users.Get("1", &got); err != nil || got.Email != "ada@example.org" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+
+	if err := users.Delete("1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetByIndex("email", "ada@example.org"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Fatalf("GetByIndex after Delete: got error %v, want %v", err, key_value.ErrorNoSuchKey)
+	}
+}
@@ -0,0 +1,345 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrFieldNotFound is returned when a JSON path does not resolve to a value.
+var ErrFieldNotFound = errors.New("field not found")
+
+// GetJSON decodes the JSON document stored at key into v.
+func GetJSON(store KV, key string, v interface{}) error {
+	value, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+	return jsonCodecFor(store).Unmarshal(value, v)
+}
+
+// SetJSON stores v at key as a JSON document. Documents are compact, with
+// HTML characters escaped, unless the store was wrapped by WithJSONOptions.
+func SetJSON(store KV, key string, v interface{}) error {
+	value, err := jsonCodecFor(store).Marshal(v)
+	if err != nil {
+		return err
+	}
+	return store.Set(key, value)
+}
+
+// SetAll stores each value of m as a JSON document at prefix+key, encoding
+// them as SetJSON does, and returns how many it wrote. Every value is encoded
+// before any is written, in key order, so an encoding error aborts the batch
+// with nothing written and names the offending key. A store error stops the
+// writes part way, leaving the entries written so far, which the count
+// reports.
+func SetAll(store KV, prefix string, m map[string]interface{}) (int, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	codec := jsonCodecFor(store)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := codec.Marshal(m[key])
+		if err != nil {
+			return 0, fmt.Errorf("encoding %q: %w", key, err)
+		}
+		values[i] = value
+	}
+
+	for i, key := range keys {
+		if err := store.Set(prefix+key, values[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+// Buffers larger than this are not kept for reuse by GetJSONPooled, so that
+// one large value does not pin its memory for the life of the component.
+const maxPooledBuffer = 64 << 10
+
+var valueBuffers = sync.Pool{
+	New: func() interface{} { return new([]byte) },
+}
+
+// GetJSONPooled is like GetJSON but reads the value into a buffer reused
+// across calls, rather than a new allocation per call, when store can append
+// a value to a buffer, as Store and kvtest.MemStore can. It suits handlers
+// that decode the same document, such as a session, on every request.
+//
+// The saving is the allocation, and copy, of the value's bytes on each call;
+// decoding still allocates the decoded values themselves. Values larger than
+// 64 KiB are not pooled. A store wrapped by the With* options is read with
+// plain GetJSON, unpooled, since reading the store beneath would bypass what
+// the options do on Get.
+func GetJSONPooled(store KV, key string, v interface{}) error {
+	appender, ok := store.(interface {
+		GetAppend(key string, dst []byte) ([]byte, error)
+	})
+	if !ok {
+		return GetJSON(store, key, v)
+	}
+
+	buf := valueBuffers.Get().(*[]byte)
+	defer func() {
+		if cap(*buf) <= maxPooledBuffer {
+			valueBuffers.Put(buf)
+		}
+	}()
+
+	value, err := appender.GetAppend(key, (*buf)[:0])
+	*buf = value
+	if err != nil {
+		return err
+	}
+	// The decoder copies everything it keeps out of value, so the buffer can
+	// be reused once it returns.
+	return jsonCodecFor(store).Unmarshal(value, v)
+}
+
+// GetMultiJSON reads each of keys and decodes its JSON document into the value
+// returned by dst for that key. Keys that do not exist are skipped, without
+// calling dst, and returned in the order given. Any other read or decode
+// error stops the batch.
+func GetMultiJSON(store KV, keys []string, dst func(key string) interface{}) ([]string, error) {
+	var missing []string
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			missing = append(missing, key)
+			continue
+		}
+		if err != nil {
+			return missing, err
+		}
+		if err := jsonCodecFor(store).Unmarshal(value, dst(key)); err != nil {
+			return missing, fmt.Errorf("decoding %q: %w", key, err)
+		}
+	}
+	return missing, nil
+}
+
+// GetMultiInto reads each of keys and decodes its JSON document into a T,
+// returning the decoded values in the order of keys along with the keys that
+// do not exist, which are skipped. Any other read or decode error stops the
+// batch.
+func GetMultiInto[T any](store KV, keys []string) ([]T, []string, error) {
+	// values never outgrows its capacity, so the pointers handed to the
+	// decoder stay valid.
+	values := make([]T, 0, len(keys))
+	missing, err := GetMultiJSON(store, keys, func(string) interface{} {
+		var v T
+		values = append(values, v)
+		return &values[len(values)-1]
+	})
+	if err != nil {
+		return nil, missing, err
+	}
+	return values, missing, nil
+}
+
+// WithJSONOptions returns store wrapped so that SetJSON encodes documents
+// with the given options: indent writes documents indented for reading by
+// people, and escapeHTML escapes <, > and & in strings as encoding/json does
+// by default. Other operations pass straight through to store.
+func WithJSONOptions(store KV, indent, escapeHTML bool) KV {
+	return &jsonOptions{KV: store, codec: JSONCodec{Indent: indent, NoEscapeHTML: !escapeHTML}}
+}
+
+type jsonOptions struct {
+	KV
+	codec JSONCodec
+}
+
+func (j *jsonOptions) unwrap() KV {
+	return j.KV
+}
+
+func jsonCodecFor(store KV) JSONCodec {
+	if opts, ok := findOption[*jsonOptions](store); ok {
+		return opts.codec
+	}
+	return JSONCodec{}
+}
+
+// GetJSONField decodes the JSON document stored at key and returns the value
+// at path. A path is a list of object keys and array indices separated by
+// dots, such as "user.addresses.0.city"; an empty path selects the whole
+// document. Values are decoded as by json.Unmarshal into an interface{}.
+func GetJSONField(store KV, key, path string) (interface{}, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return nil, err
+	}
+	return lookupJSONPath(doc, path)
+}
+
+// IncrementJSONField adds delta to the number at path in the JSON document
+// stored at key, writes the document back, and returns the new number. A
+// missing field, or a missing key, counts as zero; intermediate objects are
+// created as needed. An error is returned if the field exists but is not a
+// number. Like Increment, this is a read followed by a write and is not atomic.
+func IncrementJSONField(store KV, key, path string, delta float64) (float64, error) {
+	defer lockKey(store, key)()
+
+	doc, err := getJSONDocument(store, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var n float64
+	current, err := lookupJSONPath(doc, path)
+	switch {
+	case errors.Is(err, ErrFieldNotFound):
+	case err != nil:
+		return 0, err
+	default:
+		f, ok := current.(float64)
+		if !ok {
+			return 0, fmt.Errorf("field %q is not a number", path)
+		}
+		n = f
+	}
+	n += delta
+
+	if doc, err = setJSONPath(doc, splitJSONPath(path), n); err != nil {
+		return 0, err
+	}
+	if err := setJSONDocument(store, key, doc); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// AppendJSONArray appends items to the JSON array stored at key, creating the
+// array if the key does not exist, and returns the array's new length. An
+// error is returned if the stored value is not a JSON array. Like Increment,
+// this is a read followed by a write and is not atomic.
+func AppendJSONArray(store KV, key string, items ...interface{}) (int, error) {
+	defer lockKey(store, key)()
+
+	doc, err := getJSONDocument(store, key)
+	if err != nil {
+		return 0, err
+	}
+
+	var list []interface{}
+	if doc != nil {
+		var ok bool
+		if list, ok = doc.([]interface{}); !ok {
+			return 0, fmt.Errorf("value at %q is not a JSON array", key)
+		}
+	}
+	list = append(list, items...)
+
+	if err := setJSONDocument(store, key, list); err != nil {
+		return 0, err
+	}
+	return len(list), nil
+}
+
+// getJSONDocument decodes the JSON document at key, returning nil if the key
+// does not exist.
+func getJSONDocument(store KV, key string) (interface{}, error) {
+	value, err := store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func setJSONDocument(store KV, key string, doc interface{}) error {
+	value, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return store.Set(key, value)
+}
+
+func splitJSONPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+func lookupJSONPath(doc interface{}, path string) (interface{}, error) {
+	cur := doc
+	for _, part := range splitJSONPath(path) {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			next, ok := v[part]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+			}
+			cur = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+			}
+			cur = v[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+		}
+	}
+	return cur, nil
+}
+
+// setJSONPath returns doc with the value at path replaced by value, creating
+// objects for missing fields along the way.
+func setJSONPath(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	part := path[0]
+	switch v := doc.(type) {
+	case nil:
+		next, err := setJSONPath(nil, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{part: next}, nil
+	case map[string]interface{}:
+		next, err := setJSONPath(v[part], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		v[part] = next
+		return v, nil
+	case []interface{}:
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, strings.Join(path, "."))
+		}
+		if v[i], err = setJSONPath(v[i], path[1:], value); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("cannot set field %q in a JSON %T", part, doc)
+	}
+}
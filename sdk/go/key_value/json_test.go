@@ -0,0 +1,53 @@
+package key_value_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+type benchSession struct {
+	User    string            `json:"user"`
+	Roles   []string          `json:"roles"`
+	Expires int64             `json:"expires"`
+	Attrs   map[string]string `json:"attrs"`
+}
+
+func benchStore(b *testing.B) key_value.KV {
+	store := kvtest.NewMemStore()
+	value, err := json.Marshal(benchSession{
+		User:    "someone@example.com",
+		Roles:   []string{"admin", "editor", "viewer"},
+		Expires: 1700000000,
+		Attrs:   map[string]string{"theme": "dark", "lang": "en", "tz": "UTC"},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	store.Set("session", value)
+	return store
+}
+
+func BenchmarkGetJSON(b *testing.B) {
+	store := benchStore(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s benchSession
+		if err := key_value.GetJSON(store, "session", &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetJSONPooled(b *testing.B) {
+	store := benchStore(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s benchSession
+		if err := key_value.GetJSONPooled(store, "session", &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
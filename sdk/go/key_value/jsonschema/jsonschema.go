@@ -0,0 +1,82 @@
+// Package jsonschema validates the JSON documents written to particular keys
+// of a key_value store against a JSON Schema, so that a bad write cannot
+// leave a configuration store in a shape its readers do not expect.
+//
+// It is a separate package because it depends on
+// github.com/santhosh-tekuri/jsonschema; components that do not import it do
+// not compile that dependency.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ErrSchemaViolation is returned, wrapped in a *ViolationError, when a value
+// written to a key does not match the key's schema.
+var ErrSchemaViolation = errors.New("schema violation")
+
+// ViolationError describes why a value written to Key did not match its
+// schema.
+type ViolationError struct {
+	Key string
+	// Err is the validator's error, listing each failing part of the value.
+	Err error
+}
+
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("%v for key %q: %v", ErrSchemaViolation, e.Key, e.Err)
+}
+
+func (e *ViolationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ViolationError) Is(target error) bool {
+	return target == ErrSchemaViolation
+}
+
+// WithJSONSchema returns store wrapped so that every Set of key, including
+// those made by key_value.SetJSON and the package's other JSON helpers,
+// first validates the value against schema and returns a *ViolationError,
+// without writing, if it does not match. A value that is not valid JSON does
+// not match any schema. An error is returned if schema does not compile.
+//
+// Validation applies to key alone, compared exactly; writes to every other
+// key pass straight through, as do all reads. To validate several keys, wrap
+// the store once per key. It is built on key_value.WithValidator, so the
+// other key_value options still see through it.
+func WithJSONSchema(store key_value.KV, key string, schema []byte) (key_value.KV, error) {
+	// Each key has a compiler of its own, so the schema can be registered
+	// under a fixed URL whatever characters the key contains.
+	const url = "kv:///schema.json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, bytes.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return key_value.WithValidator(store, func(k string, value []byte) error {
+		if k != key {
+			return nil
+		}
+		dec := json.NewDecoder(bytes.NewReader(value))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return &ViolationError{Key: key, Err: err}
+		}
+		if err := compiled.Validate(doc); err != nil {
+			return &ViolationError{Key: key, Err: err}
+		}
+		return nil
+	}), nil
+}
@@ -0,0 +1,51 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/jsonschema"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+const configSchema = `{
+	"type": "object",
+	"properties": {"port": {"type": "integer", "minimum": 1}},
+	"required": ["port"]
+}`
+
+func TestWithJSONSchema(t *testing.T) {
+	store, err := jsonschema.WithJSONSchema(kvtest.NewMemStore(), "config", []byte(configSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := key_value.SetJSON(store, "config", map[string]int{"port": 8080}); err != nil {
+		t.Fatalf("SetJSON of a valid config: %v", err)
+	}
+
+	err = key_value.SetJSON(store, "config", map[string]int{"port": 0})
+	if !errors.Is(err, jsonschema.ErrSchemaViolation) {
+		t.Fatalf("SetJSON of an invalid config = %v, want ErrSchemaViolation", err)
+	}
+	var config map[string]int
+	if err := key_value.GetJSON(store, "config", &config); err != nil || config["port"] != 8080 {
+		t.Fatalf("config after rejected write = %v, %v", config, err)
+	}
+
+	if err := store.Set("other", []byte("not json")); err != nil {
+		t.Fatalf("Set of an unbound key: %v", err)
+	}
+}
+
+func TestWithJSONSchemaUnusualKey(t *testing.T) {
+	const key = "app config#1?v=50% off"
+	store, err := jsonschema.WithJSONSchema(kvtest.NewMemStore(), key, []byte(configSchema))
+	if err != nil {
+		t.Fatalf("WithJSONSchema for key %q: %v", key, err)
+	}
+	if err := key_value.SetJSON(store, key, map[string]int{"port": 0}); !errors.Is(err, jsonschema.ErrSchemaViolation) {
+		t.Fatalf("SetJSON of an invalid config = %v, want ErrSchemaViolation", err)
+	}
+}
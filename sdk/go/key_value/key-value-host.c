@@ -0,0 +1,18 @@
+//go:build !tinygo
+
+// Definitions of the key-value host imports for builds with the standard Go
+// toolchain, so that `go test ./key_value/...` links and runs on the
+// developer's machine. Every import does nothing and leaves its result area
+// zeroed: the tests exercise kvtest.MemStore and the package's wrappers, not
+// the host, and a Store used outside Spin reaches no backend. Components are
+// built with TinyGo, which leaves this file out.
+
+#include <stdint.h>
+
+void __wasm_import_key_value_open(int32_t name, int32_t name_len, int32_t ret) {}
+void __wasm_import_key_value_get(int32_t store, int32_t key, int32_t key_len, int32_t ret) {}
+void __wasm_import_key_value_set(int32_t store, int32_t key, int32_t key_len, int32_t value, int32_t value_len, int32_t ret) {}
+void __wasm_import_key_value_delete(int32_t store, int32_t key, int32_t key_len, int32_t ret) {}
+void __wasm_import_key_value_exists(int32_t store, int32_t key, int32_t key_len, int32_t ret) {}
+void __wasm_import_key_value_get_keys(int32_t store, int32_t ret) {}
+void __wasm_import_key_value_close(int32_t store) {}
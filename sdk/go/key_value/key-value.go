@@ -6,7 +6,9 @@ package key_value
 import "C"
 import (
 	"errors"
-	"fmt"
+	"strings"
+	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -26,9 +28,57 @@ func Open(name string) (Store, error) {
 	var ret C.key_value_expected_store_error_t
 	C.key_value_open(&cname, &ret)
 	if ret.is_err {
-		return 0xFFFF_FFFF, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))
+		return 0xFFFF_FFFF, &OpError{Op: "open", Store: name, Err: toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))}
+	}
+	store := *(*Store)(unsafe.Pointer(&ret.val))
+	storeNames.add(store, name)
+	return store, nil
+}
+
+// ErrOpenTimeout is returned by OpenTimeout when the host does not open the
+// store in time.
+var ErrOpenTimeout = errors.New("timed out opening store")
+
+// OpenTimeout opens the named store like Open, but gives up and returns
+// ErrOpenTimeout if the host has not answered within d.
+//
+// The host call cannot be cancelled: after a timeout it is abandoned and may
+// still be in flight. If it later succeeds the store is closed again. Because
+// a Wasm component runs goroutines on a single thread, a host call that blocks
+// the thread also delays the timeout until the call returns.
+func OpenTimeout(name string, d time.Duration) (Store, error) {
+	type result struct {
+		store Store
+		err   error
+	}
+	done := make(chan result)
+	abandoned := make(chan struct{})
+	go func() {
+		store, err := Open(name)
+		select {
+		case done <- result{store, err}:
+		case <-abandoned:
+			if err == nil {
+				Close(store)
+			}
+		}
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.store, r.err
+	case <-timer.C:
+		close(abandoned)
+		// The open may have finished while the timer fired.
+		select {
+		case r := <-done:
+			return r.store, r.err
+		default:
+		}
+		return 0xFFFF_FFFF, ErrOpenTimeout
 	}
-	return *(*Store)(unsafe.Pointer(&ret.val)), nil
 }
 
 func Get(store Store, key string) ([]byte, error) {
@@ -36,19 +86,55 @@ func Get(store Store, key string) ([]byte, error) {
 	var ret C.key_value_expected_list_u8_error_t
 	C.key_value_get(C.uint32_t(store), &ckey, &ret)
 	if ret.is_err {
-		return []byte{}, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))
+		return []byte{}, opError("get", store, key, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
 	}
 	list := (*C.key_value_list_u8_t)(unsafe.Pointer(&ret.val))
 	return C.GoBytes(unsafe.Pointer(list.ptr), C.int(list.len)), nil
 }
 
+// GetUnsafe returns the value stored at key without copying it out of the
+// memory the host wrote it into, along with a function that frees that memory.
+//
+// The returned slice aliases memory owned by the SDK. It MUST NOT be modified,
+// and it MUST NOT be used or retained after release has been called; doing so
+// reads freed memory. Prefer Get unless the copy it makes has been measured to
+// matter. Calling release more than once has no effect.
+func GetUnsafe(store Store, key string) ([]byte, func(), error) {
+	ckey := toCStr(key)
+	var ret C.key_value_expected_list_u8_error_t
+	C.key_value_get(C.uint32_t(store), &ckey, &ret)
+	if ret.is_err {
+		return nil, func() {}, opError("get", store, key, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
+	}
+	list := *(*C.key_value_list_u8_t)(unsafe.Pointer(&ret.val))
+	value := unsafe.Slice((*byte)(unsafe.Pointer(list.ptr)), int(list.len))
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { C.key_value_list_u8_free(&list) })
+	}
+	return value, release, nil
+}
+
+// GetAppend appends the value stored at key to dst and returns the extended
+// slice, in the manner of append. Reusing one buffer across many reads avoids
+// the allocation Get makes for each value. On error dst is returned unchanged.
+func GetAppend(store Store, key string, dst []byte) ([]byte, error) {
+	value, release, err := GetUnsafe(store, key)
+	if err != nil {
+		return dst, err
+	}
+	defer release()
+	return append(dst, value...), nil
+}
+
 func Set(store Store, key string, value []byte) error {
 	ckey := toCStr(key)
 	cbytes := toCBytes(value)
 	var ret C.key_value_expected_unit_error_t
 	C.key_value_set(C.uint32_t(store), &ckey, &cbytes, &ret)
 	if ret.is_err {
-		return toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))
+		return opError("set", store, key, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
 	}
 	return nil
 }
@@ -58,7 +144,7 @@ func Delete(store Store, key string) error {
 	var ret C.key_value_expected_unit_error_t
 	C.key_value_delete(C.uint32_t(store), &ckey, &ret)
 	if ret.is_err {
-		return toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))
+		return opError("delete", store, key, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
 	}
 	return nil
 }
@@ -68,7 +154,7 @@ func Exists(store Store, key string) (bool, error) {
 	var ret C.key_value_expected_bool_error_t
 	C.key_value_exists(C.uint32_t(store), &ckey, &ret)
 	if ret.is_err {
-		return false, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))
+		return false, opError("exists", store, key, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
 	}
 	return *(*bool)(unsafe.Pointer(&ret.val)), nil
 }
@@ -77,16 +163,82 @@ func GetKeys(store Store) ([]string, error) {
 	var ret C.key_value_expected_list_string_error_t
 	C.key_value_get_keys(C.uint32_t(store), &ret)
 	if ret.is_err {
-		return []string{}, toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val)))
+		return []string{}, opError("get-keys", store, "", toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
 	}
 	return fromCStrList((*C.key_value_list_string_t)(unsafe.Pointer(&ret.val))), nil
 }
 
+// GetKeysAppend appends the keys in store to dst and returns the extended
+// slice, in the manner of append, for components that list keys on a hot
+// path. Rather than allocating a string per key as GetKeys does, it copies
+// every key into one allocation and slices the keys from it, so a listing
+// costs two allocations, one of them the slice of views into the host's
+// reply, plus any growth of dst, however many keys there are. In exchange
+// the keys share memory: holding on to any one of them keeps the whole
+// listing alive. On error dst is returned unchanged.
+func GetKeysAppend(store Store, dst []string) ([]string, error) {
+	var ret C.key_value_expected_list_string_error_t
+	C.key_value_get_keys(C.uint32_t(store), &ret)
+	if ret.is_err {
+		return dst, opError("get-keys", store, "", toErr((*C.key_value_error_t)(unsafe.Pointer(&ret.val))))
+	}
+	list := (*C.key_value_list_string_t)(unsafe.Pointer(&ret.val))
+	defer C.key_value_list_string_free(list)
+
+	strs := unsafe.Slice(list.ptr, int(list.len))
+	views := make([][]byte, len(strs))
+	for i, str := range strs {
+		views[i] = unsafe.Slice((*byte)(unsafe.Pointer(str.ptr)), int(str.len))
+	}
+	return appendPacked(dst, views), nil
+}
+
+// appendPacked appends keys to dst as strings sharing a single allocation.
+func appendPacked(dst []string, keys [][]byte) []string {
+	total := 0
+	for _, key := range keys {
+		total += len(key)
+	}
+	var b strings.Builder
+	b.Grow(total)
+	for _, key := range keys {
+		b.Write(key)
+	}
+
+	packed := b.String()
+	for _, key := range keys {
+		dst = append(dst, packed[:len(key)])
+		packed = packed[len(key):]
+	}
+	return dst
+}
+
+// keysWithPrefix lists the keys in store that start with prefix. The host has
+// no prefix query, so this reads the full key list.
+func keysWithPrefix(store KV, prefix string) ([]string, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := keys[:0]
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			matched = append(matched, key)
+		}
+	}
+	return matched, nil
+}
+
 func Close(store Store) {
+	storeNames.remove(store)
 	C.key_value_close(C.uint32_t(store))
 }
 
 func toCBytes(x []byte) C.key_value_list_u8_t {
+	if len(x) == 0 {
+		return C.key_value_list_u8_t{}
+	}
 	return C.key_value_list_u8_t{ptr: (*C.uint8_t)(unsafe.Pointer(&x[0])), len: C.size_t(len(x))}
 }
 
@@ -110,19 +262,19 @@ func fromCStrList(list *C.key_value_list_string_t) []string {
 func toErr(error *C.key_value_error_t) error {
 	switch error.tag {
 	case errorKindStoreTableFull:
-		return errors.New("store table full")
+		return ErrorStoreTableFull
 	case errorKindNoSuchStore:
-		return errors.New("no such store")
+		return ErrorNoSuchStore
 	case errorKindAccessDenied:
-		return errors.New("access denied")
+		return ErrorAccessDenied
 	case errorKindInvalidStore:
-		return errors.New("invalid store")
+		return ErrorInvalidStore
 	case errorKindNoSuchKey:
-		return errors.New("no such key")
+		return ErrorNoSuchKey
 	case errorKindIo:
 		str := (*C.key_value_string_t)(unsafe.Pointer(&error.val))
-		return errors.New(fmt.Sprintf("io error: %s", C.GoStringN(str.ptr, C.int(str.len))))
+		return &Error{kind: errorKindIo, detail: C.GoStringN(str.ptr, C.int(str.len))}
 	default:
-		return errors.New(fmt.Sprintf("unrecognized error: %v", error.tag))
+		return &Error{kind: uint8(error.tag)}
 	}
 }
@@ -0,0 +1,65 @@
+package key_value
+
+import "sync"
+
+// WithKeyLocking returns store wrapped so that the read-modify-write helpers
+// of this package, such as Increment, SetVersioned, IncrementJSONField,
+// AppendJSONArray and MultiMap, serialize their updates of the same key
+// while updates of different keys proceed in parallel. Other operations pass
+// straight through to store.
+//
+// The locks only exist within this process, so they prevent lost updates
+// between goroutines sharing the returned KV but not between component
+// instances. The helpers see the locks through other With* options applied
+// on top, but not through other wrappers such as Chain.
+func WithKeyLocking(store KV) KV {
+	return &keyLocking{KV: store, locks: make(map[string]*keyLock)}
+}
+
+type keyLocking struct {
+	KV
+
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (k *keyLocking) unwrap() KV {
+	return k.KV
+}
+
+func (k *keyLocking) lockKey(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+	}
+}
+
+// lockKey locks key for a read-modify-write if store was returned by
+// WithKeyLocking, and returns the function that unlocks it.
+func lockKey(store KV, key string) func() {
+	if l, ok := findOption[interface{ lockKey(string) func() }](store); ok {
+		return l.lockKey(key)
+	}
+	return func() {}
+}
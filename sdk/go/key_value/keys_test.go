@@ -0,0 +1,63 @@
+package key_value
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// benchStore opens the default store and fills it with 1000 keys, skipping
+// the benchmark when there is no host store to open, as outside Spin, where
+// the imports are the do-nothing stubs of key-value-host.c.
+func benchStore(b *testing.B) Store {
+	if runtime.GOARCH != "wasm" {
+		b.Skip("no host store outside Spin")
+	}
+	store, err := Open("default")
+	if err != nil {
+		b.Skipf("no store to benchmark against: %v", err)
+	}
+	b.Cleanup(func() { Close(store) })
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("bench:keys:%08d", i)
+		if err := Set(store, key, nil); err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { Delete(store, key) })
+	}
+	return store
+}
+
+func BenchmarkGetKeys(b *testing.B) {
+	store := benchStore(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := GetKeys(store); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetKeysAppend lists into a reused slice, as a hot path would.
+func BenchmarkGetKeysAppend(b *testing.B) {
+	store := benchStore(b)
+	var dst []string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if dst, err = GetKeysAppend(store, dst[:0]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAppendPacked(t *testing.T) {
+	keys := [][]byte{[]byte("a"), []byte(""), []byte("bcd")}
+	got := appendPacked([]string{"x"}, keys)
+	if fmt.Sprint(got) != "[x a  bcd]" || len(got) != 4 {
+		t.Fatalf("appendPacked = %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package key_value
+
+import "io"
+
+// KV is the set of operations of a key-value store. Store implements it by
+// calling the host; other implementations can wrap a Store or, in tests,
+// stand in for one.
+//
+// Implementations report a missing key from Get with an error matching
+// ErrorNoSuchKey, and list keys in no particular order.
+type KV interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte) error
+	Delete(key string) error
+	Exists(key string) (bool, error)
+	GetKeys() ([]string, error)
+}
+
+var (
+	_ KV        = Store(0)
+	_ io.Closer = Store(0)
+)
+
+// Get is the method form of the package-level Get.
+func (s Store) Get(key string) ([]byte, error) {
+	return Get(s, key)
+}
+
+// Set is the method form of the package-level Set.
+func (s Store) Set(key string, value []byte) error {
+	return Set(s, key, value)
+}
+
+// Delete is the method form of the package-level Delete.
+func (s Store) Delete(key string) error {
+	return Delete(s, key)
+}
+
+// Exists is the method form of the package-level Exists.
+func (s Store) Exists(key string) (bool, error) {
+	return Exists(s, key)
+}
+
+// GetKeys is the method form of the package-level GetKeys.
+func (s Store) GetKeys() ([]string, error) {
+	return GetKeys(s)
+}
+
+// GetAppend is the method form of the package-level GetAppend.
+func (s Store) GetAppend(key string, dst []byte) ([]byte, error) {
+	return GetAppend(s, key, dst)
+}
+
+// Close closes the store, so that Store satisfies io.Closer. The host reports
+// no result from closing a store, and closing a handle that is not open has
+// no effect, so the returned error is always nil.
+func (s Store) Close() error {
+	Close(s)
+	return nil
+}
+
+// Handle returns the host's handle for the store, and whether the store is
+// currently open in this component, for calling host functions this package
+// does not wrap yet.
+//
+// This is an advanced, unsafe interface. The handle stays owned by s: it must
+// not be closed except through Close, and must not be used after s is
+// closed. Passing it to host functions incorrectly, or with the wrong
+// arguments, can corrupt memory or crash the component.
+func (s Store) Handle() (uint32, bool) {
+	_, open := storeNames.lookup(s)
+	return uint32(s), open
+}
+
+// unwrapper is implemented by the KVs returned by the With* options that
+// layer behavior over a store, letting helpers find an option anywhere in a
+// stack of them.
+type unwrapper interface {
+	unwrap() KV
+}
+
+// findOption returns the first KV of type T in the stack of options wrapping
+// store, starting with store itself.
+func findOption[T any](store KV) (T, bool) {
+	for store != nil {
+		if t, ok := store.(T); ok {
+			return t, true
+		}
+		u, ok := store.(unwrapper)
+		if !ok {
+			break
+		}
+		store = u.unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,179 @@
+// Package kvtest provides an in-memory key-value store and a conformance
+// suite for implementations of key_value.KV.
+package kvtest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+)
+
+// MemStore is an in-memory key_value.KV. It reports errors the same way the
+// host does and is safe for concurrent use. Once closed, every operation
+// fails with key_value.ErrorInvalidStore, as with a closed host handle.
+type MemStore struct {
+	mu     sync.Mutex
+	data   map[string][]byte
+	closed bool
+	limits Limits
+}
+
+var (
+	_ key_value.KV = (*MemStore)(nil)
+	_ io.Closer    = (*MemStore)(nil)
+)
+
+// Limits are the sizes a MemStore configured with WithLimits enforces. A
+// zero field is not limited.
+type Limits struct {
+	// MaxKeySize is the longest key, in bytes, that Set accepts; longer
+	// keys fail with key_value.ErrKeyTooLarge.
+	MaxKeySize int
+	// MaxValueSize is the largest value, in bytes, that Set accepts; larger
+	// values fail with key_value.ErrValueTooLarge.
+	MaxValueSize int
+	// MaxKeys is the most keys the store holds; a Set of a new key beyond
+	// it fails with key_value.ErrorStoreTableFull.
+	MaxKeys int
+}
+
+// DefaultLimits are limits typical of the backends a Spin store runs on:
+// keys of up to 255 bytes and values of up to 1 MiB, with no cap on the
+// number of keys.
+var DefaultLimits = Limits{MaxKeySize: 255, MaxValueSize: 1 << 20}
+
+// An Option configures a MemStore.
+type Option func(*MemStore)
+
+// WithLimits makes the store reject keys and values larger than limits
+// allows, and new keys once it is full, so tests catch oversized writes that
+// would otherwise only fail against a real backend. Without it a MemStore
+// has no limits.
+func WithLimits(limits Limits) Option {
+	return func(m *MemStore) {
+		m.limits = limits
+	}
+}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore(opts ...Option) *MemStore {
+	m := &MemStore{data: make(map[string][]byte)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *MemStore) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return []byte{}, key_value.ErrorInvalidStore
+	}
+
+	value, ok := m.data[key]
+	if !ok {
+		return []byte{}, key_value.ErrorNoSuchKey
+	}
+	return append([]byte{}, value...), nil
+}
+
+// GetAppend appends the value stored at key to dst, like
+// key_value.GetAppend.
+func (m *MemStore) GetAppend(key string, dst []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return dst, key_value.ErrorInvalidStore
+	}
+	value, ok := m.data[key]
+	if !ok {
+		return dst, key_value.ErrorNoSuchKey
+	}
+	return append(dst, value...), nil
+}
+
+func (m *MemStore) Set(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return key_value.ErrorInvalidStore
+	}
+	if err := m.checkLimits(key, value); err != nil {
+		return err
+	}
+
+	m.data[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (m *MemStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return key_value.ErrorInvalidStore
+	}
+
+	delete(m.data, key)
+	return nil
+}
+
+func (m *MemStore) Exists(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return false, key_value.ErrorInvalidStore
+	}
+
+	_, ok := m.data[key]
+	return ok, nil
+}
+
+// GetKeys returns the stored keys in sorted order.
+func (m *MemStore) GetKeys() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, key_value.ErrorInvalidStore
+	}
+
+	keys := make([]string, 0, len(m.data))
+	for key := range m.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *MemStore) checkLimits(key string, value []byte) error {
+	l := m.limits
+	if l.MaxKeySize > 0 && len(key) > l.MaxKeySize {
+		return fmt.Errorf("%w: %d bytes, limit %d", key_value.ErrKeyTooLarge, len(key), l.MaxKeySize)
+	}
+	if l.MaxValueSize > 0 && len(value) > l.MaxValueSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", key_value.ErrValueTooLarge, len(value), l.MaxValueSize)
+	}
+	if _, ok := m.data[key]; !ok && l.MaxKeys > 0 && len(m.data) >= l.MaxKeys {
+		return key_value.ErrorStoreTableFull
+	}
+	return nil
+}
+
+// Close closes the store. Like closing a host store, closing it again has no
+// effect and the returned error is always nil.
+func (m *MemStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	return nil
+}
@@ -0,0 +1,49 @@
+package kvtest_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestMemStore(t *testing.T) {
+	kvtest.RoundTripTest(t, kvtest.NewMemStore())
+}
+
+func TestMemStoreClose(t *testing.T) {
+	var c io.Closer = kvtest.NewMemStore()
+	if err := c.Close(); err != nil {
+		t.Fatalf("first Close: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	store := c.(key_value.KV)
+	if _, err := store.Get("key"); !errors.Is(err, key_value.ErrorInvalidStore) {
+		t.Fatalf("Get after Close: got error %v, want %v", err, key_value.ErrorInvalidStore)
+	}
+}
+
+func TestMemStoreLimits(t *testing.T) {
+	store := kvtest.NewMemStore(kvtest.WithLimits(kvtest.Limits{MaxKeySize: 4, MaxValueSize: 4, MaxKeys: 1}))
+
+	if err := store.Set("long key", nil); !errors.Is(err, key_value.ErrKeyTooLarge) {
+		t.Errorf("Set of a long key: got error %v, want %v", err, key_value.ErrKeyTooLarge)
+	}
+	if err := store.Set("a", []byte("large")); !errors.Is(err, key_value.ErrValueTooLarge) {
+		t.Errorf("Set of a large value: got error %v, want %v", err, key_value.ErrValueTooLarge)
+	}
+	if err := store.Set("a", []byte("ok")); err != nil {
+		t.Fatalf("Set within limits: %v", err)
+	}
+	if err := store.Set("a", []byte("new")); err != nil {
+		t.Errorf("overwrite in a full store: %v", err)
+	}
+	if err := store.Set("b", []byte("ok")); !errors.Is(err, key_value.ErrorStoreTableFull) {
+		t.Errorf("Set of a new key in a full store: got error %v, want %v", err, key_value.ErrorStoreTableFull)
+	}
+}
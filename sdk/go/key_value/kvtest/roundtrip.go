@@ -0,0 +1,174 @@
+package kvtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+)
+
+// The prefix of every key written by RoundTripTest.
+const roundTripPrefix = "kvtest:"
+
+// RoundTripTest checks that store behaves like a host key-value store across
+// a set of edge cases: empty and large values, keys and values containing
+// NUL and other control bytes, unicode, overwriting, reading after a delete,
+// and random binary values drawn from a fixed seed. Each case runs as a
+// subtest. Every key it writes starts with "kvtest:" and is deleted when the
+// case ends.
+func RoundTripTest(t *testing.T, store key_value.KV) {
+	cases := []struct {
+		name  string
+		key   string
+		value []byte
+	}{
+		{"empty value", "empty", []byte{}},
+		{"large value", "large", bytes.Repeat([]byte("0123456789abcdef"), 64*1024)},
+		{"binary key", "bin\x01\x02\x7f", []byte("binary key")},
+		{"nul key", "nul\x00key", []byte("nul key")},
+		{"nul value", "nulvalue", []byte("a\x00b\x00\x00")},
+		{"binary value", "binvalue", []byte{0x00, 0xff, 0x80, 0x7f, 0x01}},
+		{"unicode", "ключ-🔑", []byte("значение ✓")},
+		{"long key", strings.Repeat("k", 1024), []byte("long key")},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			key := roundTripPrefix + c.key
+			defer store.Delete(key)
+
+			if err := store.Set(key, c.value); err != nil {
+				t.Fatalf("Set(%q): %v", key, err)
+			}
+			got, err := store.Get(key)
+			if err != nil {
+				t.Fatalf("Get(%q): %v", key, err)
+			}
+			if !bytes.Equal(got, c.value) {
+				t.Fatalf("Get(%q) returned %d bytes, want %d bytes %q", key, len(got), len(c.value), truncate(c.value))
+			}
+			exists, err := store.Exists(key)
+			if err != nil {
+				t.Fatalf("Exists(%q): %v", key, err)
+			}
+			if !exists {
+				t.Fatalf("Exists(%q) = false after Set", key)
+			}
+			assertListed(t, store, key, true)
+		})
+	}
+
+	t.Run("overwrite", func(t *testing.T) {
+		key := roundTripPrefix + "overwrite"
+		defer store.Delete(key)
+
+		for _, value := range [][]byte{[]byte("first, and longer"), []byte("second")} {
+			if err := store.Set(key, value); err != nil {
+				t.Fatalf("Set(%q): %v", key, err)
+			}
+			got, err := store.Get(key)
+			if err != nil {
+				t.Fatalf("Get(%q): %v", key, err)
+			}
+			if !bytes.Equal(got, value) {
+				t.Fatalf("Get(%q) = %q, want %q", key, got, value)
+			}
+		}
+	})
+
+	t.Run("delete then get", func(t *testing.T) {
+		key := roundTripPrefix + "deleted"
+		if err := store.Set(key, []byte("doomed")); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+		if err := store.Delete(key); err != nil {
+			t.Fatalf("Delete(%q): %v", key, err)
+		}
+		if _, err := store.Get(key); !errors.Is(err, key_value.ErrorNoSuchKey) {
+			t.Fatalf("Get(%q) after Delete: got error %v, want %v", key, err, key_value.ErrorNoSuchKey)
+		}
+		exists, err := store.Exists(key)
+		if err != nil {
+			t.Fatalf("Exists(%q): %v", key, err)
+		}
+		if exists {
+			t.Fatalf("Exists(%q) = true after Delete", key)
+		}
+		assertListed(t, store, key, false)
+	})
+
+	t.Run("random values", func(t *testing.T) {
+		// A fixed seed keeps failures reproducible.
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < 32; i++ {
+			key := fmt.Sprintf("%srandom:%d", roundTripPrefix, i)
+			value := make([]byte, rnd.Intn(4096))
+			rnd.Read(value)
+
+			if err := store.Set(key, value); err != nil {
+				t.Fatalf("Set(%q): %v", key, err)
+			}
+			got, err := store.Get(key)
+			store.Delete(key)
+			if err != nil {
+				t.Fatalf("Get(%q): %v", key, err)
+			}
+			if !bytes.Equal(got, value) {
+				t.Fatalf("Get(%q) returned %d bytes that differ from the %d bytes written", key, len(got), len(value))
+			}
+		}
+	})
+
+	t.Run("returned value is a copy", func(t *testing.T) {
+		key := roundTripPrefix + "copy"
+		defer store.Delete(key)
+
+		value := []byte("original")
+		if err := store.Set(key, value); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+		value[0] = 'X'
+		got, err := store.Get(key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		got[1] = 'X'
+		again, err := store.Get(key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		if string(again) != "original" {
+			t.Fatalf("Get(%q) = %q after mutating slices, want %q", key, again, "original")
+		}
+	})
+}
+
+func assertListed(t *testing.T, store key_value.KV, key string, want bool) {
+	t.Helper()
+
+	keys, err := store.GetKeys()
+	if err != nil {
+		t.Fatalf("GetKeys: %v", err)
+	}
+	found := false
+	for _, k := range keys {
+		if k == key {
+			found = true
+			break
+		}
+	}
+	if found != want {
+		t.Fatalf("GetKeys lists %q = %v, want %v", key, found, want)
+	}
+}
+
+func truncate(b []byte) []byte {
+	if len(b) > 32 {
+		return b[:32]
+	}
+	return b
+}
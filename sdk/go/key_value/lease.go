@@ -0,0 +1,218 @@
+package key_value
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// LeasePrefix is prepended to a lease name to form the key its holder is
+// recorded under.
+const LeasePrefix = "lease:"
+
+// ErrLeaseLost is returned when renewing or releasing a lease that has been
+// taken by another holder, or that the holder does not hold.
+var ErrLeaseLost = errors.New("lease lost")
+
+// Lease is a named, time-limited exclusive claim shared across component
+// instances, for long-running tasks that only one instance should do at a
+// time. A holder must renew the lease before it expires to keep it.
+//
+// The lease is recorded in the store with separate reads and writes, not an
+// atomic compare-and-swap, so two instances acquiring an expired lease at the
+// same moment can both believe they hold it. More importantly, a holder that
+// fails to renew in time, because it was paused or the store was unreachable,
+// loses the lease without knowing until its next renewal, and another
+// instance can acquire it in the meantime. Work done under a lease should
+// tolerate occasionally running twice.
+//
+// A Lease is not safe for concurrent use, apart from the renewals made by
+// KeepAlive.
+type Lease struct {
+	store KV
+	key   string
+	owner string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type leaseRecord struct {
+	Owner   string `json:"owner"`
+	Expires int64  `json:"expires"`
+}
+
+// NewLease returns a handle for the lease called name, kept in store. Each
+// handle is a distinct holder.
+func NewLease(store KV, name string) (*Lease, error) {
+	owner, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{store: store, key: LeasePrefix + name, owner: owner}, nil
+}
+
+// Acquire waits until the lease is free or expired and takes it for ttl. It
+// returns ctx's error if ctx is done first.
+func (l *Lease) Acquire(ctx context.Context, ttl time.Duration) error {
+	poll := ttl / 10
+	if poll < 10*time.Millisecond {
+		poll = 10 * time.Millisecond
+	}
+	for {
+		ok, err := l.tryAcquire(ttl)
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+func (l *Lease) tryAcquire(ttl time.Duration) (bool, error) {
+	defer lockKey(l.store, l.key)()
+
+	current, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	if current.Owner != "" && current.Owner != l.owner && time.Now().UnixNano() < current.Expires {
+		return false, nil
+	}
+	if err := l.write(ttl); err != nil {
+		return false, err
+	}
+	// Read the record back, so that of two instances writing at once, the
+	// one whose write was overwritten sees it lost.
+	current, err = l.read()
+	return err == nil && current.Owner == l.owner, err
+}
+
+// Renew extends a held lease to expire ttl from now. It returns ErrLeaseLost
+// if the lease has been taken by another holder.
+func (l *Lease) Renew(ttl time.Duration) error {
+	defer lockKey(l.store, l.key)()
+
+	current, err := l.read()
+	if err != nil {
+		return err
+	}
+	if current.Owner != l.owner {
+		return ErrLeaseLost
+	}
+	return l.write(ttl)
+}
+
+// KeepAlive renews the lease for ttl every third of ttl, until Release is
+// called or ctx is done. If a renewal fails its error is sent on the
+// returned channel and renewal stops; the channel is closed when renewal
+// stops for any reason. A ttl too short to divide into a renewal interval,
+// under 3ns, is sent as an error straight away. KeepAlive must be called at
+// most once per acquisition.
+func (l *Lease) KeepAlive(ctx context.Context, ttl time.Duration) <-chan error {
+	errs := make(chan error, 1)
+	if ttl/3 <= 0 {
+		errs <- errors.New("lease ttl too short to keep alive")
+		close(errs)
+		return errs
+	}
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	stop, done := l.stop, l.done
+	go func() {
+		defer close(done)
+		defer close(errs)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := l.Renew(ttl); err != nil {
+					errs <- err
+					return
+				}
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+// Release stops any KeepAlive, waiting for a renewal in progress to finish
+// so that it cannot write the record back, and gives up the lease. It
+// returns ErrLeaseLost if the lease is not held by l, including if it has
+// expired.
+func (l *Lease) Release() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop, l.done = nil, nil
+	}
+
+	ok, err := ReleaseIfOwner(l.store, l.key, l.owner)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Token returns the token identifying l as a holder of its lease, for passing
+// to ReleaseIfOwner.
+func (l *Lease) Token() string {
+	return l.owner
+}
+
+// ReleaseIfOwner deletes the lease record at key, as written by Lease, only
+// if it is held by the holder identified by ownerToken and has not expired,
+// and reports whether it did. This keeps a holder whose lease expired from
+// deleting it after another holder has acquired it. The record is deleted
+// with DeleteIf, so the host-level race documented there remains.
+func ReleaseIfOwner(store KV, key, ownerToken string) (bool, error) {
+	raw, err := store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var record leaseRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return false, err
+	}
+	if record.Owner != ownerToken || time.Now().UnixNano() >= record.Expires {
+		return false, nil
+	}
+	return DeleteIf(store, key, raw)
+}
+
+// read returns the lease's record, or a zero record if nobody holds it.
+func (l *Lease) read() (leaseRecord, error) {
+	var record leaseRecord
+	raw, err := l.store.Get(l.key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return record, nil
+	}
+	if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(raw, &record)
+	return record, err
+}
+
+func (l *Lease) write(ttl time.Duration) error {
+	raw, err := json.Marshal(leaseRecord{Owner: l.owner, Expires: time.Now().Add(ttl).UnixNano()})
+	if err != nil {
+		return err
+	}
+	return l.store.Set(l.key, raw)
+}
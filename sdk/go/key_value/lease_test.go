@@ -0,0 +1,67 @@
+package key_value_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestLease(t *testing.T) {
+	store := kvtest.NewMemStore()
+	first, _ := key_value.NewLease(store, "job")
+	second, _ := key_value.NewLease(store, "job")
+	ctx := context.Background()
+
+	if err := first.Acquire(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := second.Acquire(short, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire of held lease: %v, want DeadlineExceeded", err)
+	}
+	if err := second.Renew(time.Minute); !errors.Is(err, key_value.ErrLeaseLost) {
+		t.Errorf("Renew by non-holder: %v, want ErrLeaseLost", err)
+	}
+	if err := second.Release(); !errors.Is(err, key_value.ErrLeaseLost) {
+		t.Errorf("Release by non-holder: %v, want ErrLeaseLost", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Acquire(ctx, time.Minute); err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+}
+
+func TestLeaseKeepAlive(t *testing.T) {
+	store := kvtest.NewMemStore()
+	lease, _ := key_value.NewLease(store, "job")
+	ctx := context.Background()
+
+	if err := <-lease.KeepAlive(ctx, time.Nanosecond); err == nil {
+		t.Error("KeepAlive with a 1ns ttl did not fail")
+	}
+
+	const ttl = 60 * time.Millisecond
+	if err := lease.Acquire(ctx, ttl); err != nil {
+		t.Fatal(err)
+	}
+	errs := lease.KeepAlive(ctx, ttl)
+	time.Sleep(2 * ttl)
+	if err := lease.Release(); err != nil {
+		t.Fatalf("Release after keeping the lease alive past its ttl: %v", err)
+	}
+	if err, ok := <-errs; ok {
+		t.Errorf("KeepAlive failed: %v", err)
+	}
+	time.Sleep(ttl)
+	if ok, err := store.Exists(key_value.LeasePrefix + "job"); err != nil || ok {
+		t.Errorf("lease record exists after Release = %v, %v, want false", ok, err)
+	}
+}
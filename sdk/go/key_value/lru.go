@@ -0,0 +1,121 @@
+package key_value
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRUStore is a KV holding at most a fixed number of keys, evicting the least
+// recently used key from the underlying store when a Set would exceed the
+// capacity. It makes a store usable as a bounded cache.
+//
+// The access order is kept in memory by this process only. It knows only the
+// keys read or written through the LRUStore since it was created: keys
+// already in the store, or written through other handles or by other
+// component instances, are neither counted nor evicted until they are
+// accessed through it.
+type LRUStore struct {
+	store    KV
+	capacity int
+
+	mu    sync.Mutex
+	order *list.List // of string, most recently used first
+	items map[string]*list.Element
+}
+
+var _ KV = (*LRUStore)(nil)
+
+// NewLRUStore returns an LRUStore over store holding at most capacity keys.
+// It panics if capacity is less than 1.
+func NewLRUStore(store KV, capacity int) *LRUStore {
+	if capacity < 1 {
+		panic("key_value: NewLRUStore called with capacity < 1")
+	}
+	return &LRUStore{
+		store:    store,
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Capacity returns the maximum number of keys the store holds.
+func (l *LRUStore) Capacity() int {
+	return l.capacity
+}
+
+// Len returns the number of keys currently tracked.
+func (l *LRUStore) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+// touch marks key as the most recently used and returns the keys that must
+// be evicted to bring the store back within capacity.
+func (l *LRUStore) touch(key string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		l.order.MoveToFront(e)
+		return nil
+	}
+	l.items[key] = l.order.PushFront(key)
+
+	var evicted []string
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(string))
+		evicted = append(evicted, oldest.Value.(string))
+	}
+	return evicted
+}
+
+func (l *LRUStore) forget(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.order.Remove(e)
+		delete(l.items, key)
+	}
+}
+
+func (l *LRUStore) evict(keys []string) error {
+	for _, key := range keys {
+		if err := l.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *LRUStore) Get(key string) ([]byte, error) {
+	value, err := l.store.Get(key)
+	if err != nil {
+		return value, err
+	}
+	return value, l.evict(l.touch(key))
+}
+
+func (l *LRUStore) Set(key string, value []byte) error {
+	if err := l.store.Set(key, value); err != nil {
+		return err
+	}
+	return l.evict(l.touch(key))
+}
+
+func (l *LRUStore) Delete(key string) error {
+	l.forget(key)
+	return l.store.Delete(key)
+}
+
+// Exists reports whether key exists without counting as a use of it.
+func (l *LRUStore) Exists(key string) (bool, error) {
+	return l.store.Exists(key)
+}
+
+func (l *LRUStore) GetKeys() ([]string, error) {
+	return l.store.GetKeys()
+}
@@ -0,0 +1,31 @@
+package key_value_test
+
+import (
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestLRUStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.NewLRUStore(backing, 2)
+
+	store.Set("a", []byte("1"))
+	store.Set("b", []byte("2"))
+	if _, err := store.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("c", []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if ok, err := backing.Exists(key); err != nil || ok != want {
+			t.Errorf("Exists(%q) = %v, %v after eviction, want %v", key, ok, err, want)
+		}
+	}
+	if store.Len() != 2 || store.Capacity() != 2 {
+		t.Errorf("Len, Capacity = %d, %d, want 2, 2", store.Len(), store.Capacity())
+	}
+}
@@ -0,0 +1,43 @@
+package key_value
+
+import "errors"
+
+// MergeStrategy decides, for MergeMap, which value a field keeps when it is
+// both already stored and in the updates.
+type MergeStrategy int
+
+const (
+	// MergeLastWriteWins replaces stored fields with the updated values.
+	MergeLastWriteWins MergeStrategy = iota
+	// MergeKeepExisting keeps stored fields, adding only new ones.
+	MergeKeepExisting
+)
+
+// MergeMap merges updates into the JSON object of strings stored at key,
+// creating it if the key does not exist, and resolves each field present in
+// both according to strategy. It suits shared configuration where different
+// writers update different fields of the same map.
+//
+// The merge is a separate read and write. Concurrent merges of the same key
+// by different instances can lose each other's fields; within this process
+// they do not overlap if the store was wrapped by WithKeyLocking.
+func MergeMap(store KV, key string, updates map[string]string, strategy MergeStrategy) error {
+	defer lockKey(store, key)()
+
+	current := map[string]string{}
+	err := GetJSON(store, key, &current)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return err
+	}
+	if current == nil {
+		// The stored document was null.
+		current = map[string]string{}
+	}
+	for field, value := range updates {
+		if _, ok := current[field]; ok && strategy == MergeKeepExisting {
+			continue
+		}
+		current[field] = value
+	}
+	return SetJSON(store, key, current)
+}
@@ -0,0 +1,115 @@
+package key_value
+
+import "time"
+
+// Middleware wraps a KV to add behaviour, such as the With* options of this
+// package, each of which has a Middleware constructor below. Options that
+// can fail to apply, such as jsonschema.WithJSONSchema, have none.
+type Middleware func(KV) KV
+
+// Wrap returns base wrapped by each of mws. The first middleware is the
+// outermost: it sees each call first and passes it on to the second, and so
+// on, with the last calling base itself. For example,
+//
+//	Wrap(store, KeyLockingMiddleware(), ScalarCodecMiddleware(ScalarText))
+//
+// is WithKeyLocking(WithScalarCodec(store, ScalarText)).
+//
+// Order does not matter for the options that only configure the helpers,
+// which find them through any number of layers: WithKeyLocking,
+// WithScalarCodec, WithJSONOptions and WithCapacityThreshold. It does matter
+// for the options that change or inspect keys and values, since each sees
+// what the layers above it pass down:
+//
+//   - WithValidator goes above WithEnvelope, so that it checks the value the
+//     caller wrote rather than the sealed bytes.
+//   - WithQuota goes below WithEnvelope, so that it counts the sealed bytes
+//     the store actually holds.
+//   - WithHistory goes above WithSoftDelete, so that a soft delete records
+//     the live value and the history never holds tombstones.
+//   - WithReadCache and WithCopyOnRead go above every option that
+//     transforms values, so that they cache and copy what the caller reads.
+//
+// For example, Wrap(store, ReadCacheMiddleware(time.Minute, 100),
+// ValidatorMiddleware(validate), EnvelopeMiddleware(opts),
+// QuotaMiddleware("tenant:", 1<<20)) follows these rules.
+func Wrap(base KV, mws ...Middleware) KV {
+	store := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		store = mws[i](store)
+	}
+	return store
+}
+
+// KeyLockingMiddleware returns WithKeyLocking as a Middleware.
+func KeyLockingMiddleware() Middleware {
+	return WithKeyLocking
+}
+
+// ScalarCodecMiddleware returns WithScalarCodec with codec as a Middleware.
+func ScalarCodecMiddleware(codec ScalarCodec) Middleware {
+	return func(store KV) KV { return WithScalarCodec(store, codec) }
+}
+
+// JSONOptionsMiddleware returns WithJSONOptions with indent and escapeHTML as
+// a Middleware.
+func JSONOptionsMiddleware(indent, escapeHTML bool) Middleware {
+	return func(store KV) KV { return WithJSONOptions(store, indent, escapeHTML) }
+}
+
+// ValidatorMiddleware returns WithValidator with validate as a Middleware.
+func ValidatorMiddleware(validate func(key string, value []byte) error) Middleware {
+	return func(store KV) KV { return WithValidator(store, validate) }
+}
+
+// CopyOnReadMiddleware returns WithCopyOnRead as a Middleware.
+func CopyOnReadMiddleware() Middleware {
+	return WithCopyOnRead
+}
+
+// EvictOnFullMiddleware returns WithEvictOnFull with evict as a Middleware.
+func EvictOnFullMiddleware(evict func(store KV) error) Middleware {
+	return func(store KV) KV { return WithEvictOnFull(store, evict) }
+}
+
+// AccessTrackingMiddleware returns WithAccessTracking as a Middleware.
+func AccessTrackingMiddleware() Middleware {
+	return WithAccessTracking
+}
+
+// SoftDeleteMiddleware returns WithSoftDelete as a Middleware.
+func SoftDeleteMiddleware() Middleware {
+	return WithSoftDelete
+}
+
+// ReadCacheMiddleware returns WithReadCache with ttl and maxEntries as a
+// Middleware.
+func ReadCacheMiddleware(ttl time.Duration, maxEntries int) Middleware {
+	return func(store KV) KV { return WithReadCache(store, ttl, maxEntries) }
+}
+
+// HistoryMiddleware returns WithHistory with max as a Middleware.
+func HistoryMiddleware(max int) Middleware {
+	return func(store KV) KV { return WithHistory(store, max) }
+}
+
+// CapacityThresholdMiddleware returns WithCapacityThreshold with n as a
+// Middleware.
+func CapacityThresholdMiddleware(n int) Middleware {
+	return func(store KV) KV { return WithCapacityThreshold(store, n) }
+}
+
+// WriteTimestampsMiddleware returns WithWriteTimestamps as a Middleware.
+func WriteTimestampsMiddleware() Middleware {
+	return WithWriteTimestamps
+}
+
+// QuotaMiddleware returns WithQuota with prefix and maxBytes as a Middleware.
+func QuotaMiddleware(prefix string, maxBytes int64) Middleware {
+	return func(store KV) KV { return WithQuota(store, prefix, maxBytes) }
+}
+
+// EnvelopeMiddleware returns WithEnvelope with opts as a Middleware.
+func EnvelopeMiddleware(opts EnvelopeOptions) Middleware {
+	return func(store KV) KV { return WithEnvelope(store, opts) }
+}
@@ -0,0 +1,24 @@
+// Package msgpack provides a key_value.Codec that encodes values as
+// MessagePack, which is more compact and faster to encode than JSON for
+// small structured values.
+//
+// It is a separate package because it depends on
+// github.com/vmihailenco/msgpack/v5; components that do not import it do not
+// compile that dependency.
+package msgpack
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec is a key_value.Codec using MessagePack. Struct fields are named by
+// their `msgpack` tags, falling back to the field name.
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,55 @@
+package msgpack
+
+import (
+	"reflect"
+	"testing"
+)
+
+type sample struct {
+	Name  string            `msgpack:"name"`
+	Count int64             `msgpack:"count"`
+	Tags  []string          `msgpack:"tags"`
+	Attrs map[string]string `msgpack:"attrs"`
+	Data  []byte            `msgpack:"data"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := sample{
+		Name:  "widget",
+		Count: -42,
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]string{"color": "blue"},
+		Data:  []byte{0x00, 0xff},
+	}
+
+	var c Codec
+	data, err := c.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got sample
+	if err := c.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestSmallerThanJSON(t *testing.T) {
+	data, err := Codec{}.Marshal(map[string]int{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// {"a":1,"b":2} is 13 bytes of JSON.
+	if len(data) >= 13 {
+		t.Errorf("encoded %d bytes, want fewer than JSON's 13", len(data))
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	var v sample
+	if err := (Codec{}).Unmarshal([]byte{0xc1}, &v); err == nil {
+		t.Fatal("Unmarshal of an invalid encoding succeeded")
+	}
+}
@@ -0,0 +1,90 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MultiMapPrefix is prepended to a logical key to form the key MultiMap stores
+// its values under.
+const MultiMapPrefix = "multimap:"
+
+// MultiMap stores a set of string values for each logical key. The values of
+// a key are kept as a single JSON array of strings, in insertion order and
+// without duplicates, at MultiMapPrefix+key, so they can be read directly by
+// other tools.
+type MultiMap struct {
+	store KV
+}
+
+// NewMultiMap returns a multimap backed by store.
+func NewMultiMap(store KV) *MultiMap {
+	return &MultiMap{store: store}
+}
+
+// Add adds value to the values of key. Adding a value that is already present
+// has no effect.
+func (m *MultiMap) Add(key, value string) error {
+	defer lockKey(m.store, MultiMapPrefix+key)()
+
+	values, err := m.Get(key)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		if v == value {
+			return nil
+		}
+	}
+	return m.put(key, append(values, value))
+}
+
+// Get returns the values of key, or an empty slice if it has none.
+func (m *MultiMap) Get(key string) ([]string, error) {
+	raw, err := m.store.Get(MultiMapPrefix + key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Remove removes value from the values of key. The underlying key is deleted
+// once its last value is removed.
+func (m *MultiMap) Remove(key, value string) error {
+	defer lockKey(m.store, MultiMapPrefix+key)()
+
+	values, err := m.Get(key)
+	if err != nil {
+		return err
+	}
+
+	kept := values[:0]
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == len(values) {
+		return nil
+	}
+	if len(kept) == 0 {
+		return m.store.Delete(MultiMapPrefix + key)
+	}
+	return m.put(key, kept)
+}
+
+func (m *MultiMap) put(key string, values []string) error {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return m.store.Set(MultiMapPrefix+key, raw)
+}
@@ -0,0 +1,177 @@
+package key_value
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MultiSetAtomic writes every item to store, and if any write fails, tries to
+// put the affected keys back the way they were: keys that held a value are
+// set to it again and keys that did not exist are deleted. The original
+// write error is returned, along with any failure to restore.
+//
+// This is best-effort compensation, not a transaction. Other readers can see
+// the partial update while it is in progress, writes made by others to the
+// same keys in the meantime are overwritten by the restore, and a crash in
+// the middle leaves the partial update in place.
+func MultiSetAtomic(store KV, items map[string][]byte) error {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	type snapshot struct {
+		value   []byte
+		existed bool
+	}
+	prior := make(map[string]snapshot, len(keys))
+	for _, key := range keys {
+		value, err := store.Get(key)
+		switch {
+		case err == nil:
+			prior[key] = snapshot{value: value, existed: true}
+		case errors.Is(err, ErrorNoSuchKey):
+			prior[key] = snapshot{}
+		default:
+			return err
+		}
+	}
+
+	for i, key := range keys {
+		err := store.Set(key, items[key])
+		if err == nil {
+			continue
+		}
+
+		// Restore every key up to and including the failed one, which may
+		// have been partially written.
+		var restoreErr error
+		for _, k := range keys[:i+1] {
+			var err error
+			if p := prior[k]; p.existed {
+				err = store.Set(k, p.value)
+			} else {
+				err = store.Delete(k)
+			}
+			if err != nil && restoreErr == nil {
+				restoreErr = fmt.Errorf("restoring %q: %w", k, err)
+			}
+		}
+		if restoreErr != nil {
+			return fmt.Errorf("setting %q: %w; rollback failed: %v", key, err, restoreErr)
+		}
+		return fmt.Errorf("setting %q: %w", key, err)
+	}
+	return nil
+}
+
+// SwapValues exchanges the values stored at keys a and b, as when flipping
+// "active" and "staging" configurations. If only one of the keys exists, its
+// value moves to the other and it is deleted; if neither exists, nothing
+// changes.
+//
+// The swap is not atomic. Both values are read and then written with
+// separate host calls, so a write from another instance in between is lost,
+// and a crash part way through can leave both keys holding the same value.
+// When both keys exist the writes go through MultiSetAtomic, which undoes a
+// failed write but not a crash; when one key is being moved, a failure to
+// delete it leaves the value at both keys.
+func SwapValues(store KV, a, b string) error {
+	if a == b {
+		return nil
+	}
+	first, second := a, b
+	if second < first {
+		first, second = second, first
+	}
+	defer lockKey(store, first)()
+	defer lockKey(store, second)()
+
+	valueA, okA, err := GetIfExists(store, a)
+	if err != nil {
+		return err
+	}
+	valueB, okB, err := GetIfExists(store, b)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case okA && okB:
+		return MultiSetAtomic(store, map[string][]byte{a: valueB, b: valueA})
+	case okA:
+		if err := store.Set(b, valueA); err != nil {
+			return err
+		}
+		return store.Delete(a)
+	case okB:
+		if err := store.Set(a, valueB); err != nil {
+			return err
+		}
+		return store.Delete(b)
+	}
+	return nil
+}
+
+// Condition is one key of a MultiCAS: the value the key must currently hold,
+// and the value to write if every condition of the batch holds.
+type Condition struct {
+	Key string
+	// Expected is the value the key must hold, compared with the stored
+	// bytes as they are, so an empty Expected does not match a nil stored by
+	// SetNil. It is ignored if Absent is set.
+	Expected []byte
+	// Absent requires the key not to exist.
+	Absent bool
+	// Value is written to the key.
+	Value []byte
+}
+
+// MultiCAS writes the Value of every condition if each key currently holds
+// its Expected value, or is absent as required, and reports whether it did.
+// If any condition does not hold, nothing is written and false is returned
+// without error. It suits small invariants spanning several keys, such as
+// moving an item from one collection to another. Each key may appear only
+// once.
+//
+// MultiCAS is best-effort. Every key is read and checked, and then the new
+// values are written with MultiSetAtomic; another instance can change a key
+// between the check and the write, and that change is overwritten. A write
+// that fails part way is compensated as MultiSetAtomic does, but a crash
+// leaves the partial update in place.
+func MultiCAS(store KV, conds []Condition) (bool, error) {
+	items := make(map[string][]byte, len(conds))
+	keys := make([]string, 0, len(conds))
+	for _, c := range conds {
+		if _, ok := items[c.Key]; ok {
+			return false, fmt.Errorf("key %q appears more than once", c.Key)
+		}
+		items[c.Key] = c.Value
+		keys = append(keys, c.Key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		defer lockKey(store, key)()
+	}
+
+	for _, c := range conds {
+		value, ok, err := GetIfExists(store, c.Key)
+		if err != nil {
+			return false, err
+		}
+		if c.Absent {
+			if ok {
+				return false, nil
+			}
+		} else if !ok || !bytes.Equal(value, c.Expected) {
+			return false, nil
+		}
+	}
+	if err := MultiSetAtomic(store, items); err != nil {
+		return false, err
+	}
+	return true, nil
+}
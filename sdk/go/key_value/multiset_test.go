@@ -0,0 +1,134 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+// failingStore fails every Set of one key.
+type failingStore struct {
+	*kvtest.MemStore
+	failKey string
+}
+
+var errInjected = errors.New("injected failure")
+
+func (f *failingStore) Set(key string, value []byte) error {
+	if key == f.failKey {
+		return errInjected
+	}
+	return f.MemStore.Set(key, value)
+}
+
+func TestMultiSetAtomicRollsBack(t *testing.T) {
+	store := &failingStore{MemStore: kvtest.NewMemStore(), failKey: "b"}
+	store.MemStore.Set("a", []byte("old a"))
+
+	err := key_value.MultiSetAtomic(store, map[string][]byte{
+		"a": []byte("new a"),
+		"b": []byte("new b"),
+		"c": []byte("new c"),
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("MultiSetAtomic error = %v, want %v", err, errInjected)
+	}
+
+	got, err := store.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old a" {
+		t.Errorf("a = %q after rollback, want %q", got, "old a")
+	}
+	for _, key := range []string{"b", "c"} {
+		if exists, _ := store.Exists(key); exists {
+			t.Errorf("%s exists after rollback", key)
+		}
+	}
+}
+
+func TestMultiSetAtomicFailedRollback(t *testing.T) {
+	store := &failingStore{MemStore: kvtest.NewMemStore(), failKey: "b"}
+	store.MemStore.Set("b", []byte("old b"))
+
+	err := key_value.MultiSetAtomic(store, map[string][]byte{"a": []byte("new a"), "b": []byte("new b")})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("MultiSetAtomic with a failed rollback = %v, want it to wrap the write's error", err)
+	}
+}
+
+func TestMultiSetAtomic(t *testing.T) {
+	store := kvtest.NewMemStore()
+	items := map[string][]byte{"a": []byte("1"), "b": []byte("2")}
+	if err := key_value.MultiSetAtomic(store, items); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range items {
+		got, err := store.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSwapValuesKeepsNil(t *testing.T) {
+	store := kvtest.NewMemStore()
+	key_value.SetNil(store, "a")
+	store.Set("b", []byte{})
+
+	if err := key_value.SwapValues(store, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := key_value.IsNil(store, "b"); err != nil || !ok {
+		t.Errorf("IsNil(b) after swap = %v, %v, want true", ok, err)
+	}
+	if ok, err := key_value.IsNil(store, "a"); err != nil || ok {
+		t.Errorf("IsNil(a) after swap = %v, %v, want false", ok, err)
+	}
+
+	if err := key_value.SwapValues(store, "b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := key_value.IsNil(store, "c"); err != nil || !ok {
+		t.Errorf("IsNil(c) after move = %v, %v, want true", ok, err)
+	}
+}
+
+func TestMultiCAS(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("from", []byte("item"))
+
+	move := []key_value.Condition{
+		{Key: "from", Expected: []byte("item"), Value: []byte{}},
+		{Key: "to", Absent: true, Value: []byte("item")},
+	}
+	if ok, err := key_value.MultiCAS(store, move); err != nil || !ok {
+		t.Fatalf("MultiCAS = %v, %v, want true", ok, err)
+	}
+	if ok, err := key_value.MultiCAS(store, move); err != nil || ok {
+		t.Fatalf("MultiCAS with failed conditions = %v, %v, want false", ok, err)
+	}
+	got, err := store.Get("to")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "item" {
+		t.Errorf("to = %q, want %q", got, "item")
+	}
+}
+
+func TestMultiCASNil(t *testing.T) {
+	store := kvtest.NewMemStore()
+	key_value.SetNil(store, "k")
+
+	cond := []key_value.Condition{{Key: "k", Expected: []byte{}, Value: []byte("v")}}
+	if ok, err := key_value.MultiCAS(store, cond); err != nil || ok {
+		t.Errorf("MultiCAS expecting an empty value of a nil key = %v, %v, want false", ok, err)
+	}
+}
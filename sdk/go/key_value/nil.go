@@ -0,0 +1,51 @@
+package key_value
+
+import "errors"
+
+// nilValue is the sentinel stored by SetNil. The host stores byte lists, so
+// it has no nil of its own.
+const nilValue = "\x00nil\x00"
+
+// SetNil stores an explicit nil at key, distinct from an empty value, for
+// modelling fields that are present but unset. GetOK returns a nil value for
+// it and IsNil reports it.
+//
+// A nil is stored as a reserved 5-byte sentinel, so a value set with Set that
+// happens to hold exactly those bytes also reads as nil.
+func SetNil(store KV, key string) error {
+	return store.Set(key, []byte(nilValue))
+}
+
+// IsNil reports whether key holds a nil stored by SetNil. It returns false
+// for missing keys.
+func IsNil(store KV, key string) (bool, error) {
+	value, err := store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return string(value) == nilValue, nil
+}
+
+// GetOK returns the value stored at key and whether the key exists, telling
+// apart the three cases a plain Get cannot: a missing key returns ok false;
+// a nil stored by SetNil returns a nil value; and an empty value returns a
+// non-nil, empty value.
+func GetOK(store KV, key string) (value []byte, ok bool, err error) {
+	value, err = store.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if string(value) == nilValue {
+		return nil, true, nil
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, true, nil
+}
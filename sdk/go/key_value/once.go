@@ -0,0 +1,62 @@
+package key_value
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// onceLocks serializes Once calls for the same key within this process,
+// whichever store they are made on.
+var onceLocks = &keyLocking{locks: make(map[string]*keyLock)}
+
+// Once returns the value stored at key, first calling init and storing its
+// result if the key does not exist. It suits expensive one-time setup whose
+// result must survive restarts, such as generating a signing key.
+//
+// Concurrent calls for the same key within this process wait for each other,
+// so init runs at most once per process; if init fails nothing is stored and
+// the next call runs it again. Checking for the key and storing the result
+// are separate host calls, so component instances starting together can each
+// run init, with the last to store its result winning. Where that matters,
+// init should produce a value that is safe to replace.
+func Once(store KV, key string, init func() ([]byte, error)) ([]byte, error) {
+	defer onceLocks.lockKey(key)()
+
+	value, err := store.Get(key)
+	if err == nil || !errors.Is(err, ErrorNoSuchKey) {
+		return value, err
+	}
+	value, err = init()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Set(key, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// EnsureSecret returns the secret stored at key, first generating size bytes
+// from crypto/rand and storing them if the key does not exist, so that a
+// component can keep a stable signing or encryption key across restarts
+// without managing an external secret. An existing value is returned
+// whatever its length; a size that is not positive is an error.
+//
+// It is built on Once and has the same race: component instances starting
+// together against an empty key can each generate a secret, and all but the
+// last to store theirs return a secret that is then replaced. Each instance
+// keeps using the secret it was returned until it calls EnsureSecret again,
+// so values signed by one may fail to verify in another until then. Call it
+// once at startup, or seed the key before deploying more than one instance.
+func EnsureSecret(store KV, key string, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, errors.New("secret size must be positive")
+	}
+	return Once(store, key, func() ([]byte, error) {
+		secret := make([]byte, size)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		return secret, nil
+	})
+}
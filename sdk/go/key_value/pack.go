@@ -0,0 +1,149 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// PackStore is a KV that keeps all of its keys packed into a single value of
+// an underlying store, for workloads with thousands of tiny related keys
+// where per-key overhead dominates. The pack is a JSON object mapping each
+// key to its value, base64 encoded.
+//
+// The pack is read from the store on first use and kept in memory, so Get,
+// Exists and GetKeys make no host calls after that; call Reload to see
+// changes made through other handles. Every Set or Delete reads the current
+// pack from the store, changes it and writes the whole pack back, so writes
+// cost two host calls and grow with the size of the pack, and writes to the
+// same pack from different instances at the same time can be lost.
+type PackStore struct {
+	store KV
+	key   string
+
+	mu     sync.Mutex
+	loaded bool
+	pack   map[string][]byte
+}
+
+var _ KV = (*PackStore)(nil)
+
+// NewPackStore returns a PackStore packing its keys into the value at key in
+// store.
+func NewPackStore(store KV, key string) *PackStore {
+	return &PackStore{store: store, key: key}
+}
+
+// Reload discards the in-memory copy of the pack, so that it is read from
+// the store again on next use.
+func (p *PackStore) Reload() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loaded = false
+	p.pack = nil
+}
+
+func (p *PackStore) read() (map[string][]byte, error) {
+	pack := map[string][]byte{}
+	raw, err := p.store.Get(p.key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return pack, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &pack); err != nil {
+		return nil, fmt.Errorf("decoding pack %q: %w", p.key, err)
+	}
+	if pack == nil {
+		pack = map[string][]byte{}
+	}
+	return pack, nil
+}
+
+// cached returns the in-memory pack, loading it if need be. p.mu must be held.
+func (p *PackStore) cached() (map[string][]byte, error) {
+	if !p.loaded {
+		pack, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+		p.pack, p.loaded = pack, true
+	}
+	return p.pack, nil
+}
+
+func (p *PackStore) update(fn func(pack map[string][]byte)) error {
+	defer lockKey(p.store, p.key)()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pack, err := p.read()
+	if err != nil {
+		return err
+	}
+	fn(pack)
+	raw, err := json.Marshal(pack)
+	if err != nil {
+		return err
+	}
+	if err := p.store.Set(p.key, raw); err != nil {
+		return err
+	}
+	p.pack, p.loaded = pack, true
+	return nil
+}
+
+func (p *PackStore) Get(key string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pack, err := p.cached()
+	if err != nil {
+		return []byte{}, err
+	}
+	value, ok := pack[key]
+	if !ok {
+		return []byte{}, ErrorNoSuchKey
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (p *PackStore) Set(key string, value []byte) error {
+	value = append([]byte{}, value...)
+	return p.update(func(pack map[string][]byte) { pack[key] = value })
+}
+
+func (p *PackStore) Delete(key string) error {
+	return p.update(func(pack map[string][]byte) { delete(pack, key) })
+}
+
+func (p *PackStore) Exists(key string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pack, err := p.cached()
+	if err != nil {
+		return false, err
+	}
+	_, ok := pack[key]
+	return ok, nil
+}
+
+func (p *PackStore) GetKeys() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pack, err := p.cached()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(pack))
+	for key := range pack {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,23 @@
+package key_value_test
+
+import (
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestPackStore(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.NewPackStore(backing, "pack")
+	kvtest.RoundTripTest(t, store)
+
+	store.Set("a", []byte("1"))
+	keys, err := backing.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "pack" {
+		t.Fatalf("backing store holds keys %q, want just the pack", keys)
+	}
+}
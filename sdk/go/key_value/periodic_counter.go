@@ -0,0 +1,107 @@
+package key_value
+
+import (
+	"errors"
+	"time"
+)
+
+// PeriodicCounterPrefix is prepended to the keys PeriodicCounter keeps its
+// buckets in.
+const PeriodicCounterPrefix = "periodic:"
+
+// Period is the calendar period a PeriodicCounter buckets its counts by.
+type Period int
+
+const (
+	// PeriodHour buckets counts by UTC hour.
+	PeriodHour Period = iota
+	// PeriodDay buckets counts by UTC day.
+	PeriodDay
+	// PeriodMonth buckets counts by UTC month.
+	PeriodMonth
+)
+
+// layout is the time layout of the bucket names of p.
+func (p Period) layout() string {
+	switch p {
+	case PeriodHour:
+		return "2006-01-02T15"
+	case PeriodMonth:
+		return "2006-01"
+	default:
+		return "2006-01-02"
+	}
+}
+
+// start returns the start of the period containing t, in UTC.
+func (p Period) start(t time.Time) time.Time {
+	t = t.UTC()
+	switch p {
+	case PeriodHour:
+		return t.Truncate(time.Hour)
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// next returns the start of the period after the one starting at t.
+func (p Period) next(t time.Time) time.Time {
+	switch p {
+	case PeriodHour:
+		return t.Add(time.Hour)
+	case PeriodMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// PeriodicCounter counts events per metric in calendar buckets, such as
+// daily active users.
+//
+// The count of a metric for a period is stored with SetInt64's encoding at
+// PeriodicCounterPrefix+metric+":"+bucket, where bucket is the UTC start of
+// the period formatted as "2006-01-02T15" for hours, "2006-01-02" for days
+// and "2006-01" for months; for example "periodic:signups:2024-03-07".
+// Buckets are never deleted by PeriodicCounter.
+type PeriodicCounter struct {
+	store  KV
+	period Period
+}
+
+// NewPeriodicCounter returns a PeriodicCounter keeping its buckets in store,
+// one per period.
+func NewPeriodicCounter(store KV, period Period) *PeriodicCounter {
+	return &PeriodicCounter{store: store, period: period}
+}
+
+// Inc adds one to metric's count for the current period. Like Increment it
+// is a read followed by a write and is not atomic.
+func (c *PeriodicCounter) Inc(metric string) error {
+	_, err := Increment(c.store, c.key(metric, c.period.start(time.Now())), 1)
+	return err
+}
+
+// Total returns the sum of metric's counts for every period from the one
+// containing from through the one containing to. It reads one key per
+// period in the range.
+func (c *PeriodicCounter) Total(metric string, from, to time.Time) (int64, error) {
+	var total int64
+	for t := c.period.start(from); !t.After(to); t = c.period.next(t) {
+		n, err := GetInt64(c.store, c.key(metric, t))
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (c *PeriodicCounter) key(metric string, start time.Time) string {
+	return PeriodicCounterPrefix + metric + ":" + start.Format(c.period.layout())
+}
@@ -0,0 +1,94 @@
+package key_value
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// SelfTestPrefix is prepended to a random id to form the temporary key
+// SelfTest writes.
+const SelfTestPrefix = "__selftest:"
+
+// AllowedStores returns those of candidates that this component can open, in
+// the order given, so that it can adapt to the stores configured for it
+// rather than failing later with ErrorNoSuchStore.
+//
+// The host does not expose the names of the stores configured for a
+// component, so AllowedStores probes each candidate by opening and closing
+// it. Candidates the host does not recognize, or that the component may not
+// access, are left out; any other error stops the probe.
+func AllowedStores(candidates []string) ([]string, error) {
+	var allowed []string
+	for _, name := range candidates {
+		err := CheckStore(name)
+		if errors.Is(err, ErrorNoSuchStore) || errors.Is(err, ErrorAccessDenied) {
+			continue
+		}
+		if err != nil {
+			return allowed, err
+		}
+		allowed = append(allowed, name)
+	}
+	return allowed, nil
+}
+
+// CheckStore opens the named store and closes it again, returning the error
+// Open would, such as ErrorNoSuchStore or ErrorAccessDenied, so that a
+// component can check the store names in its configuration at startup
+// rather than failing mid-request. To check a store and keep it open, call
+// Open, which also reports these errors immediately.
+func CheckStore(name string) error {
+	store, err := Open(name)
+	if err != nil {
+		return err
+	}
+	Close(store)
+	return nil
+}
+
+// SelfTest checks that store works end to end before a component starts
+// serving, so a misconfigured or read-only store is reported at startup
+// with a clear error rather than by the first real request. It writes a
+// random value to SelfTestPrefix followed by a random id, reads it back and
+// compares it, and deletes it again, checking that it is gone. The id keeps
+// instances testing the same store at once apart. The key is deleted even
+// if a step fails part way.
+func SelfTest(store KV) error {
+	id, err := randomID()
+	if err != nil {
+		return err
+	}
+	key := SelfTestPrefix + id
+	value := []byte(id)
+
+	if err := store.Set(key, value); err != nil {
+		return fmt.Errorf("self-test write of %q: %w", key, err)
+	}
+	deleted := false
+	defer func() {
+		if !deleted {
+			store.Delete(key)
+		}
+	}()
+
+	got, err := store.Get(key)
+	if err != nil {
+		return fmt.Errorf("self-test read of %q: %w", key, err)
+	}
+	if !bytes.Equal(got, value) {
+		return fmt.Errorf("self-test read of %q returned %q, want %q", key, got, value)
+	}
+	if err := store.Delete(key); err != nil {
+		return fmt.Errorf("self-test delete of %q: %w", key, err)
+	}
+	deleted = true
+	exists, err := store.Exists(key)
+	if err != nil {
+		return fmt.Errorf("self-test exists check of %q: %w", key, err)
+	}
+	if exists {
+		return fmt.Errorf("self-test key %q still exists after delete", key)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+// corruptingStore returns a wrong value from every Get.
+type corruptingStore struct {
+	*kvtest.MemStore
+}
+
+func (c corruptingStore) Get(key string) ([]byte, error) {
+	value, err := c.MemStore.Get(key)
+	return append(value, '!'), err
+}
+
+func TestSelfTest(t *testing.T) {
+	store := kvtest.NewMemStore()
+	if err := key_value.SelfTest(store); err != nil {
+		t.Fatalf("SelfTest of a working store: %v", err)
+	}
+	if keys, _ := store.GetKeys(); len(keys) != 0 {
+		t.Errorf("SelfTest left %q behind", keys)
+	}
+
+	limited := kvtest.NewMemStore(kvtest.WithLimits(kvtest.Limits{MaxValueSize: 8}))
+	if err := key_value.SelfTest(limited); !errors.Is(err, key_value.ErrValueTooLarge) {
+		t.Errorf("SelfTest of a store rejecting the write = %v, want ErrValueTooLarge", err)
+	}
+	if keys, _ := limited.GetKeys(); len(keys) != 0 {
+		t.Errorf("failed SelfTest left %q behind", keys)
+	}
+
+	corrupting := corruptingStore{kvtest.NewMemStore()}
+	if err := key_value.SelfTest(corrupting); err == nil {
+		t.Error("SelfTest of a store returning wrong values succeeded")
+	}
+	if keys, _ := corrupting.GetKeys(); len(keys) != 0 {
+		t.Errorf("SelfTest failing on read-back left %q behind", keys)
+	}
+}
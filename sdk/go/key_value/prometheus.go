@@ -0,0 +1,121 @@
+package key_value
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CounterPrefix is prepended to the name of a counter, incremented with
+// Increment or CounterBuffer, for ExportPrometheus to export it.
+const CounterPrefix = "counter:"
+
+// ExportPrometheus writes the counters under CounterPrefix and the
+// histograms under HistogramPrefix in store in the Prometheus text
+// exposition format, for serving from a component's /metrics endpoint.
+//
+// The counter at CounterPrefix+name is exported as a counter called name,
+// and the buckets of the Histogram called name as a histogram called name
+// with cumulative le buckets and a _count; Histogram keeps no sum, so there
+// is no _sum. Characters not allowed in metric names are replaced by "_".
+// Only buckets that have been observed are written. Metrics are written in
+// name order. ExportPrometheus lists every key in the store and reads every
+// metric key.
+func ExportPrometheus(store KV, w io.Writer) error {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	counters := make(map[string]int64)
+	histograms := make(map[string]map[float64]int64)
+	for _, key := range keys {
+		switch {
+		case strings.HasPrefix(key, CounterPrefix):
+			n, err := GetInt64(store, key)
+			if errors.Is(err, ErrorNoSuchKey) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			counters[promName(strings.TrimPrefix(key, CounterPrefix))] += n
+		case strings.HasPrefix(key, HistogramPrefix):
+			rest := strings.TrimPrefix(key, HistogramPrefix)
+			i := strings.LastIndex(rest, ":")
+			if i < 0 {
+				continue
+			}
+			bound, err := strconv.ParseFloat(rest[i+1:], 64)
+			if err != nil {
+				continue
+			}
+			n, err := GetInt64(store, key)
+			if errors.Is(err, ErrorNoSuchKey) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			name := promName(rest[:i])
+			if histograms[name] == nil {
+				histograms[name] = make(map[float64]int64)
+			}
+			histograms[name][bound] += n
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, name := range sortedKeys(counters) {
+		fmt.Fprintf(bw, "# TYPE %s counter\n%s %d\n", name, name, counters[name])
+	}
+	for _, name := range sortedKeys(histograms) {
+		buckets := histograms[name]
+		bounds := make([]float64, 0, len(buckets))
+		for bound := range buckets {
+			bounds = append(bounds, bound)
+		}
+		sort.Float64s(bounds)
+
+		fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		var total int64
+		for _, bound := range bounds {
+			total += buckets[bound]
+			if !math.IsInf(bound, 1) {
+				fmt.Fprintf(bw, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), total)
+			}
+		}
+		fmt.Fprintf(bw, "%s_bucket{le=\"+Inf\"} %d\n%s_count %d\n", name, total, name, total)
+	}
+	return bw.Flush()
+}
+
+// promName makes name a valid Prometheus metric name.
+func promName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || c == ':' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9'
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
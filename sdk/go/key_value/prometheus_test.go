@@ -0,0 +1,38 @@
+package key_value_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestExportPrometheus(t *testing.T) {
+	store := kvtest.NewMemStore()
+	if _, err := key_value.Increment(store, key_value.CounterPrefix+"requests-total", 3); err != nil {
+		t.Fatal(err)
+	}
+	h := key_value.NewHistogram(store, []float64{0.1, 1})
+	for _, v := range []float64{0.05, 0.5, 0.7, 5} {
+		if err := h.Observe("latency_seconds", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b strings.Builder
+	if err := key_value.ExportPrometheus(store, &b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE requests_total counter
+requests_total 3
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_count 4
+`
+	if b.String() != want {
+		t.Errorf("ExportPrometheus wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
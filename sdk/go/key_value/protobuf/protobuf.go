@@ -0,0 +1,31 @@
+// Package protobuf stores protocol buffer messages in a key_value store, for
+// components that already use protobuf for their domain types. Messages are
+// kept in the protobuf binary wire format.
+//
+// It is a separate package because it depends on google.golang.org/protobuf;
+// components that do not import it do not compile that dependency.
+package protobuf
+
+import (
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"google.golang.org/protobuf/proto"
+)
+
+// SetProto stores m at key in its binary wire format.
+func SetProto(store key_value.KV, key string, m proto.Message) error {
+	value, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return store.Set(key, value)
+}
+
+// GetProto decodes the message stored at key into m, replacing its contents.
+// It returns key_value.ErrorNoSuchKey if the key does not exist.
+func GetProto(store key_value.KV, key string, m proto.Message) error {
+	value, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(value, m)
+}
@@ -0,0 +1,49 @@
+package protobuf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRoundTrip(t *testing.T) {
+	want, err := structpb.NewStruct(map[string]interface{}{
+		"name":  "widget",
+		"count": 42,
+		"tags":  []interface{}{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := kvtest.NewMemStore()
+	if err := SetProto(store, "widget", want); err != nil {
+		t.Fatal(err)
+	}
+	got := &structpb.Struct{}
+	if err := GetProto(store, "widget", got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, want) {
+		t.Fatalf("round trip = %v, want %v", got, want)
+	}
+}
+
+func TestGetProtoMissing(t *testing.T) {
+	err := GetProto(kvtest.NewMemStore(), "missing", &structpb.Struct{})
+	if !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Fatalf("GetProto of a missing key: got error %v, want %v", err, key_value.ErrorNoSuchKey)
+	}
+}
+
+func TestGetProtoInvalid(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("bad", []byte{0xff})
+	if err := GetProto(store, "bad", &structpb.Struct{}); err == nil {
+		t.Fatal("GetProto of an invalid encoding succeeded")
+	}
+}
@@ -0,0 +1,56 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrQuotaExceeded is returned by a store wrapped with WithQuota when a write
+// would take a prefix over its quota.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// WithQuota returns store wrapped so that a Set of a key starting with prefix
+// fails with an error matching ErrQuotaExceeded, without writing, if it
+// would bring the total size of the values under prefix, as PrefixSize
+// counts it, above maxBytes. Overwriting a key counts only the change in its
+// size. Wrap once per prefix to give several tenants of a shared store each
+// their own quota. Other operations, and writes to other keys, pass straight
+// through to store.
+//
+// Every checked Set first runs PrefixSize, reading every value under the
+// prefix, so quotas suit prefixes with few keys or infrequent writes. The
+// check and the write are separate host calls: concurrent writers can each
+// pass the check and together exceed the quota.
+func WithQuota(store KV, prefix string, maxBytes int64) KV {
+	return &quota{KV: store, prefix: prefix, maxBytes: maxBytes}
+}
+
+type quota struct {
+	KV
+	prefix   string
+	maxBytes int64
+}
+
+func (q *quota) unwrap() KV {
+	return q.KV
+}
+
+func (q *quota) Set(key string, value []byte) error {
+	if !strings.HasPrefix(key, q.prefix) {
+		return q.KV.Set(key, value)
+	}
+
+	_, used, err := PrefixSize(q.KV, q.prefix)
+	if err != nil {
+		return err
+	}
+	old, err := Size(q.KV, key)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return err
+	}
+	if after := used - int64(old) + int64(len(value)); after > q.maxBytes {
+		return fmt.Errorf("%w: %q would hold %d bytes, limit %d", ErrQuotaExceeded, q.prefix, after, q.maxBytes)
+	}
+	return q.KV.Set(key, value)
+}
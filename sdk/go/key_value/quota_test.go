@@ -0,0 +1,49 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestQuota(t *testing.T) {
+	store := key_value.WithQuota(kvtest.NewMemStore(), "tenant:", 10)
+
+	if err := store.Set("tenant:a", []byte("123456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("tenant:b", []byte("12345")); !errors.Is(err, key_value.ErrQuotaExceeded) {
+		t.Fatalf("Set over quota = %v, want ErrQuotaExceeded", err)
+	}
+	if ok, _ := store.Exists("tenant:b"); ok {
+		t.Error("a rejected Set was written")
+	}
+	if err := store.Set("tenant:b", []byte("1234")); err != nil {
+		t.Fatalf("Set up to the quota: %v", err)
+	}
+
+	// Overwriting counts only the change in size.
+	if err := store.Set("tenant:a", []byte("12")); err != nil {
+		t.Fatalf("shrinking overwrite: %v", err)
+	}
+	if err := store.Set("tenant:a", []byte("1234567")); !errors.Is(err, key_value.ErrQuotaExceeded) {
+		t.Fatalf("growing overwrite over quota = %v, want ErrQuotaExceeded", err)
+	}
+	if err := store.Set("tenant:a", []byte("123456")); err != nil {
+		t.Fatalf("growing overwrite up to the quota: %v", err)
+	}
+
+	// Deleting frees space for new writes.
+	if err := store.Delete("tenant:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("tenant:c", []byte("123456")); err != nil {
+		t.Fatalf("Set after Delete freed space: %v", err)
+	}
+
+	if err := store.Set("other", make([]byte, 100)); err != nil {
+		t.Errorf("Set outside the prefix: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RateLimitPrefix is prepended to the keys RateLimiter keeps its counters in.
+const RateLimitPrefix = "ratelimit:"
+
+// RateLimiter limits how often an action may happen per key, sharing its
+// counts across every component instance using the same store.
+//
+// Each key gets one counter per fixed window, stored at
+// RateLimitPrefix+key+":"+n where n numbers the window since the Unix epoch.
+// The counters are updated with Increment, which is not atomic on the host,
+// so concurrent requests can be undercounted and the limit is best-effort.
+type RateLimiter struct {
+	store KV
+}
+
+// NewRateLimiter returns a rate limiter that keeps its counters in store.
+func NewRateLimiter(store KV) *RateLimiter {
+	return &RateLimiter{store: store}
+}
+
+// Allow records a request for key and reports whether it is within limit
+// requests for the current window.
+func (r *RateLimiter) Allow(key string, limit int, window time.Duration) (bool, error) {
+	if window <= 0 {
+		return false, errors.New("rate limit window must be positive")
+	}
+
+	n := time.Now().UnixNano() / int64(window)
+	count, err := Increment(r.store, rateLimitKey(key, n), 1)
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		// First request of a new window: drop the previous window's counter
+		// so counters don't accumulate. Failing to do so is harmless.
+		r.store.Delete(rateLimitKey(key, n-1))
+	}
+	return count <= int64(limit), nil
+}
+
+func rateLimitKey(key string, window int64) string {
+	return fmt.Sprintf("%s%s:%d", RateLimitPrefix, key, window)
+}
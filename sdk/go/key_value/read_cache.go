@@ -0,0 +1,181 @@
+package key_value
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Source reports where GetWithSource found a value.
+type Source int
+
+const (
+	// SourceStore means the read reached the backing store.
+	SourceStore Source = iota
+	// SourceCache means the read was answered by a cache in front of the
+	// backing store, such as WithReadCache or a Chain tier other than the
+	// last.
+	SourceCache
+)
+
+func (s Source) String() string {
+	if s == SourceCache {
+		return "cache"
+	}
+	return "store"
+}
+
+// GetWithSource reads key like store.Get and reports whether the value came
+// from a cache, for measuring how effective caching is. store must be the
+// KV returned by WithReadCache or a *Chain for a read to be attributed to a
+// cache; caches wrapped in any other KV are not looked through, since that
+// KV's Get may do more than the cache's, and every other store reports
+// SourceStore. A miss is reported with the source that answered it.
+func GetWithSource(store KV, key string) ([]byte, Source, error) {
+	if s, ok := store.(interface {
+		GetWithSource(string) ([]byte, Source, error)
+	}); ok {
+		return s.GetWithSource(key)
+	}
+	value, err := store.Get(key)
+	return value, SourceStore, err
+}
+
+// WithReadCache returns store wrapped so that values read with Get are kept
+// in memory for ttl and served from there, cutting repeated host calls for
+// hot keys in a long-lived component instance. At most maxEntries values are
+// kept, evicting the least recently used. Setting or deleting a key through
+// the returned KV drops its cached value; missing keys are not cached.
+//
+// The cache cannot see writes made through other handles or by other
+// instances, so a value read through it can be up to ttl out of date.
+// WithReadCache panics if maxEntries is less than 1.
+func WithReadCache(store KV, ttl time.Duration, maxEntries int) KV {
+	if maxEntries < 1 {
+		panic("key_value: WithReadCache called with maxEntries < 1")
+	}
+	return &readCache{
+		KV:         store,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+type readCache struct {
+	KV
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+	// gen counts the writes made through the cache. A value read from the
+	// backing store is only cached if no write happened during the read,
+	// since it may be older than the write.
+	gen uint64
+}
+
+type cacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func (c *readCache) unwrap() KV {
+	return c.KV
+}
+
+func (c *readCache) lookup(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expires) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+func (c *readCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *readCache) store(key string, value []byte, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	entry := &cacheEntry{key: key, value: value, expires: time.Now().Add(c.ttl)}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *readCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+func (c *readCache) Get(key string) ([]byte, error) {
+	value, _, err := c.GetWithSource(key)
+	return value, err
+}
+
+// GetWithSource is Get, also reporting whether the value was cached.
+func (c *readCache) GetWithSource(key string) ([]byte, Source, error) {
+	if value, ok := c.lookup(key); ok {
+		return append([]byte{}, value...), SourceCache, nil
+	}
+	gen := c.generation()
+	value, err := c.KV.Get(key)
+	if err != nil {
+		return value, SourceStore, err
+	}
+	c.store(key, append([]byte{}, value...), gen)
+	return value, SourceStore, nil
+}
+
+// Set and Delete forget the key once the write is done, so that a value
+// cached by a Get that read the store during the write is dropped too.
+func (c *readCache) Set(key string, value []byte) error {
+	defer c.forget(key)
+	return c.KV.Set(key, value)
+}
+
+func (c *readCache) Delete(key string) error {
+	defer c.forget(key)
+	return c.KV.Delete(key)
+}
+
+func (c *readCache) Exists(key string) (bool, error) {
+	if _, ok := c.lookup(key); ok {
+		return true, nil
+	}
+	return c.KV.Exists(key)
+}
@@ -0,0 +1,164 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestReadCache(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.WithReadCache(backing, time.Hour, 2)
+	backing.Set("k", []byte("old"))
+
+	if got, _ := store.Get("k"); string(got) != "old" {
+		t.Fatalf("Get(k) = %q, want %q", got, "old")
+	}
+	backing.Set("k", []byte("behind the cache"))
+	if got, _ := store.Get("k"); string(got) != "old" {
+		t.Errorf("Get(k) = %q, want the cached %q", got, "old")
+	}
+
+	if err := store.Set("k", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Get("k"); string(got) != "new" {
+		t.Errorf("Get(k) = %q after Set, want %q", got, "new")
+	}
+	if err := store.Delete("k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("k"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Errorf("Get(k) after Delete = %v, want ErrorNoSuchKey", err)
+	}
+	backing.Set("k", []byte("created behind the cache"))
+	if got, _ := store.Get("k"); string(got) != "created behind the cache" {
+		t.Errorf("Get(k) = %q, want the miss not to have been cached", got)
+	}
+}
+
+func TestReadCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.WithReadCache(backing, time.Hour, 2)
+	for _, key := range []string{"a", "b"} {
+		backing.Set(key, []byte(key))
+		store.Get(key)
+	}
+	store.Get("a")
+	backing.Set("c", []byte("c"))
+	store.Get("c")
+
+	// Reading b from the store caches it again, so it is checked last.
+	for _, tt := range []struct {
+		key  string
+		want key_value.Source
+	}{
+		{"a", key_value.SourceCache},
+		{"c", key_value.SourceCache},
+		{"b", key_value.SourceStore},
+	} {
+		if _, source, _ := key_value.GetWithSource(store, tt.key); source != tt.want {
+			t.Errorf("%s read from %v, want %v", tt.key, source, tt.want)
+		}
+	}
+}
+
+func TestReadCacheExpires(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.WithReadCache(backing, time.Millisecond, 10)
+	backing.Set("k", []byte("old"))
+	store.Get("k")
+	backing.Set("k", []byte("new"))
+
+	time.Sleep(2 * time.Millisecond)
+	if got, _ := store.Get("k"); string(got) != "new" {
+		t.Errorf("Get(k) = %q after the ttl, want %q", got, "new")
+	}
+}
+
+// pausingReadStore pauses the first Get of "k" after reading it, until
+// release is closed.
+type pausingReadStore struct {
+	*kvtest.MemStore
+	read, release chan struct{}
+}
+
+func (s *pausingReadStore) Get(key string) ([]byte, error) {
+	value, err := s.MemStore.Get(key)
+	if key == "k" && s.read != nil {
+		close(s.read)
+		s.read = nil
+		<-s.release
+	}
+	return value, err
+}
+
+func TestReadCacheSetDuringGet(t *testing.T) {
+	read := make(chan struct{})
+	backing := &pausingReadStore{MemStore: kvtest.NewMemStore(), read: read, release: make(chan struct{})}
+	backing.MemStore.Set("k", []byte("old"))
+	store := key_value.WithReadCache(backing, time.Hour, 10)
+
+	done := make(chan struct{})
+	go func() {
+		store.Get("k")
+		close(done)
+	}()
+	<-read
+	if err := store.Set("k", []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	close(backing.release)
+	<-done
+
+	if got, _ := store.Get("k"); string(got) != "new" {
+		t.Errorf("Get(k) = %q, want %q: a read overlapping the Set cached the old value", got, "new")
+	}
+}
+
+func TestGetWithSource(t *testing.T) {
+	cached := kvtest.NewMemStore()
+	cached.Set("k", []byte("v"))
+	readCache := key_value.WithReadCache(cached, time.Hour, 10)
+	readCache.Get("k")
+
+	cacheTier, backing := kvtest.NewMemStore(), kvtest.NewMemStore()
+	cacheTier.Set("fast", []byte("v"))
+	backing.Set("slow", []byte("v"))
+	chain := key_value.NewChain([]key_value.KV{cacheTier, backing}, key_value.WithNegativeCache(time.Hour))
+	chain.Get("absent")
+
+	tests := []struct {
+		name    string
+		store   key_value.KV
+		key     string
+		want    key_value.Source
+		wantErr error
+	}{
+		{"plain store", cached, "k", key_value.SourceStore, nil},
+		{"read cache hit", readCache, "k", key_value.SourceCache, nil},
+		{"read cache miss", readCache, "absent", key_value.SourceStore, key_value.ErrorNoSuchKey},
+		{"wrapped read cache", key_value.WithKeyLocking(readCache), "k", key_value.SourceStore, nil},
+		{"chain cache tier", chain, "fast", key_value.SourceCache, nil},
+		{"chain last tier", chain, "slow", key_value.SourceStore, nil},
+		{"chain negative cache", chain, "absent", key_value.SourceCache, key_value.ErrorNoSuchKey},
+		{"chain miss", chain, "unknown", key_value.SourceStore, key_value.ErrorNoSuchKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source, err := key_value.GetWithSource(tt.store, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetWithSource(%q) error = %v, want %v", tt.key, err, tt.wantErr)
+			}
+			if err == nil && string(got) != "v" {
+				t.Errorf("GetWithSource(%q) = %q, want %q", tt.key, got, "v")
+			}
+			if source != tt.want {
+				t.Errorf("GetWithSource(%q) source = %v, want %v", tt.key, source, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package key_value
+
+import (
+	"bytes"
+	"io"
+)
+
+// GetReaderAt reads the value stored at key and returns it as an io.ReaderAt,
+// along with its length, for libraries that parse blobs by random access,
+// such as archive/zip. The whole value is read into memory first. It returns
+// ErrorNoSuchKey if the key does not exist.
+func GetReaderAt(store KV, key string) (io.ReaderAt, int64, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(value), int64(len(value)), nil
+}
@@ -0,0 +1,116 @@
+package key_value
+
+import (
+	"errors"
+	"sync"
+)
+
+// Op is one operation recorded by Recording.
+type Op struct {
+	// Type is the operation: "get", "set", "delete", "exists" or
+	// "get-keys", as in OpError.
+	Type string
+	// Key is the key operated on. It is empty for get-keys.
+	Key string
+	// Value is the value written by a set or returned by a successful get,
+	// and nil otherwise.
+	Value []byte
+	Err   error
+}
+
+// Recording returns store wrapped so that every operation through it is
+// recorded, in order, along with the function that returns a copy of the
+// operations recorded so far. It is meant for tests and debugging, to see
+// what a handler actually did to a store, and for replay-based regression
+// tests with Replay.
+//
+// The log is kept in memory and grows without bound, with a copy of every
+// value read or written, so Recording is not suited to long-running
+// production use. The helpers of this package record the host operations
+// they make, not the helper calls themselves.
+func Recording(store KV) (KV, func() []Op) {
+	r := &recording{KV: store}
+	return r, r.log
+}
+
+type recording struct {
+	KV
+
+	mu  sync.Mutex
+	ops []Op
+}
+
+func (r *recording) unwrap() KV {
+	return r.KV
+}
+
+func (r *recording) record(op Op) {
+	if op.Value != nil {
+		op.Value = append([]byte{}, op.Value...)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, op)
+}
+
+func (r *recording) log() []Op {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Op(nil), r.ops...)
+}
+
+func (r *recording) Get(key string) ([]byte, error) {
+	value, err := r.KV.Get(key)
+	op := Op{Type: "get", Key: key, Err: err}
+	if err == nil {
+		op.Value = value
+	}
+	r.record(op)
+	return value, err
+}
+
+func (r *recording) Set(key string, value []byte) error {
+	err := r.KV.Set(key, value)
+	r.record(Op{Type: "set", Key: key, Value: value, Err: err})
+	return err
+}
+
+func (r *recording) Delete(key string) error {
+	err := r.KV.Delete(key)
+	r.record(Op{Type: "delete", Key: key, Err: err})
+	return err
+}
+
+func (r *recording) Exists(key string) (bool, error) {
+	exists, err := r.KV.Exists(key)
+	r.record(Op{Type: "exists", Key: key, Err: err})
+	return exists, err
+}
+
+func (r *recording) GetKeys() ([]string, error) {
+	keys, err := r.KV.GetKeys()
+	r.record(Op{Type: "get-keys", Err: err})
+	return keys, err
+}
+
+// Replay applies the successful sets and deletes of ops to store, in order,
+// skipping reads and failed operations, and stops at the first error. A
+// delete of a key store does not have is not an error.
+func Replay(store KV, ops []Op) error {
+	for _, op := range ops {
+		if op.Err != nil {
+			continue
+		}
+		switch op.Type {
+		case "set":
+			if err := store.Set(op.Key, op.Value); err != nil {
+				return err
+			}
+		case "delete":
+			if err := store.Delete(op.Key); err != nil && !errors.Is(err, ErrorNoSuchKey) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestRecording(t *testing.T) {
+	store, log := key_value.Recording(kvtest.NewMemStore())
+	store.Set("a", []byte("1"))
+	store.Get("a")
+	store.Get("missing")
+	store.Delete("a")
+
+	ops := log()
+	if len(ops) != 4 {
+		t.Fatalf("recorded %d operations, want 4: %+v", len(ops), ops)
+	}
+	if ops[0].Type != "set" || ops[0].Key != "a" || string(ops[0].Value) != "1" {
+		t.Errorf("ops[0] = %+v", ops[0])
+	}
+	if ops[1].Type != "get" || string(ops[1].Value) != "1" || ops[1].Err != nil {
+		t.Errorf("ops[1] = %+v", ops[1])
+	}
+	if ops[2].Value != nil || !errors.Is(ops[2].Err, key_value.ErrorNoSuchKey) {
+		t.Errorf("ops[2] = %+v", ops[2])
+	}
+
+	replayed := kvtest.NewMemStore()
+	replayed.Set("b", []byte("2"))
+	if err := key_value.Replay(replayed, ops[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := replayed.Get("a"); err != nil || string(value) != "1" {
+		t.Fatalf("Get after Replay = %q, %v", value, err)
+	}
+}
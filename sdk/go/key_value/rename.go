@@ -0,0 +1,110 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RenamePattern moves every key matching from to the key formed from to,
+// and returns how many it moved, to relabel a namespace of keys in a schema
+// migration, as in RenamePattern(store, "v1:*", "v2:*").
+//
+// Each "*" in from matches any run of characters, including none, with the
+// earliest match taken when several are possible; the text each one matched
+// is substituted for the "*"s of to in order. to may have fewer "*"s than
+// from, dropping the later captures, but not more. No other character is
+// special.
+//
+// Every target key is computed before anything is moved. If two keys would
+// move to the same target, or a target already exists, an error naming it
+// is returned and nothing is moved. Each key is then moved by writing its
+// value under the new key and deleting the old one; the moves are separate
+// host calls, not a transaction, so an error part way leaves the keys moved
+// so far under their new names, and the count reports them.
+func RenamePattern(store KV, from, to string) (int, error) {
+	fromParts := strings.Split(from, "*")
+	toParts := strings.Split(to, "*")
+	if len(toParts) > len(fromParts) {
+		return 0, fmt.Errorf("pattern %q has more wildcards than %q", to, from)
+	}
+
+	keys, err := store.GetKeys()
+	if err != nil {
+		return 0, err
+	}
+	var sources, targets []string
+	taken := make(map[string]string)
+	for _, key := range keys {
+		captures, ok := matchWildcards(fromParts, key)
+		if !ok {
+			continue
+		}
+		var b strings.Builder
+		for i, part := range toParts {
+			if i > 0 {
+				b.WriteString(captures[i-1])
+			}
+			b.WriteString(part)
+		}
+		target := b.String()
+		if other, ok := taken[target]; ok {
+			return 0, fmt.Errorf("both %q and %q would be renamed to %q", other, key, target)
+		}
+		taken[target] = key
+		sources = append(sources, key)
+		targets = append(targets, target)
+	}
+	for _, target := range targets {
+		exists, err := store.Exists(target)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			return 0, fmt.Errorf("key %q already exists", target)
+		}
+	}
+
+	renamed := 0
+	for i, key := range sources {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return renamed, err
+		}
+		if err := store.Set(targets[i], value); err != nil {
+			return renamed, err
+		}
+		if err := store.Delete(key); err != nil {
+			return renamed, err
+		}
+		renamed++
+	}
+	return renamed, nil
+}
+
+// matchWildcards matches key against a pattern split at its "*"s, returning
+// the text each "*" matched.
+func matchWildcards(parts []string, key string) ([]string, bool) {
+	if len(parts) == 1 {
+		return nil, key == parts[0]
+	}
+	first, last := parts[0], parts[len(parts)-1]
+	if len(key) < len(first)+len(last) || !strings.HasPrefix(key, first) || !strings.HasSuffix(key, last) {
+		return nil, false
+	}
+	rest := key[len(first) : len(key)-len(last)]
+
+	captures := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return nil, false
+		}
+		captures = append(captures, rest[:i])
+		rest = rest[i+len(part):]
+	}
+	return append(captures, rest), true
+}
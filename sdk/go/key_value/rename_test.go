@@ -0,0 +1,95 @@
+package key_value_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestRenamePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		from, to string
+		want     []string // keys after the rename, sorted
+		moved    int
+		wantErr  bool
+	}{
+		{
+			name: "prefix",
+			keys: []string{"v1:a", "v1:b", "other"},
+			from: "v1:*", to: "v2:*",
+			want: []string{"other", "v2:a", "v2:b"}, moved: 2,
+		},
+		{
+			name: "several wildcards",
+			keys: []string{"user:1:name", "user:2:email", "user:3"},
+			from: "user:*:*", to: "*/profile/*",
+			want: []string{"1/profile/name", "2/profile/email", "user:3"}, moved: 2,
+		},
+		{
+			name: "earliest match",
+			keys: []string{"a:b:c"},
+			from: "*:*", to: "[*][*]",
+			want: []string{"[a][b:c]"}, moved: 1,
+		},
+		{
+			name: "empty capture",
+			keys: []string{"tmp:", "tmp:x"},
+			from: "tmp:*", to: "cache:*",
+			want: []string{"cache:", "cache:x"}, moved: 2,
+		},
+		{
+			name: "fewer wildcards in to",
+			keys: []string{"log:2024:01"},
+			from: "log:*:*", to: "archive:*",
+			want: []string{"archive:2024"}, moved: 1,
+		},
+		{
+			name: "more wildcards in to",
+			keys: []string{"a:1"},
+			from: "a:*", to: "*:*",
+			want: []string{"a:1"}, wantErr: true,
+		},
+		{
+			name: "two sources one target",
+			keys: []string{"log:2024:01", "log:2024:02"},
+			from: "log:*:*", to: "archive:*",
+			want: []string{"log:2024:01", "log:2024:02"}, wantErr: true,
+		},
+		{
+			name: "target exists",
+			keys: []string{"v1:a", "v1:b", "v2:b"},
+			from: "v1:*", to: "v2:*",
+			want: []string{"v1:a", "v1:b", "v2:b"}, wantErr: true,
+		},
+		{
+			name: "no wildcard",
+			keys: []string{"exact", "exactly"},
+			from: "exact", to: "renamed",
+			want: []string{"exactly", "renamed"}, moved: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := kvtest.NewMemStore()
+			for _, key := range tt.keys {
+				store.Set(key, []byte(key))
+			}
+
+			moved, err := key_value.RenamePattern(store, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenamePattern = %d, %v, want error %v", moved, err, tt.wantErr)
+			}
+			if moved != tt.moved {
+				t.Errorf("moved %d keys, want %d", moved, tt.moved)
+			}
+			keys, _ := store.GetKeys()
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("keys = %q, want %q", keys, tt.want)
+			}
+		})
+	}
+}
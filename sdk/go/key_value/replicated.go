@@ -0,0 +1,171 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInconsistent is returned, wrapped in an *InconsistentError, when a
+// quorum read finds no majority among the replicas of a ReplicatedStore.
+var ErrInconsistent = errors.New("replicas disagree")
+
+// InconsistentError reports the values the replicas held for a key when no
+// majority of them agreed.
+type InconsistentError struct {
+	Key string
+	// Values holds each replica's value, in replica order, with nil for a
+	// replica that does not have the key or whose read failed.
+	Values [][]byte
+}
+
+func (e *InconsistentError) Error() string {
+	return fmt.Sprintf("%v for key %q", ErrInconsistent, e.Key)
+}
+
+func (e *InconsistentError) Is(target error) bool {
+	return target == ErrInconsistent
+}
+
+// ReadMode selects how a ReplicatedStore reads.
+type ReadMode int
+
+const (
+	// ReadFirst reads from the first replica only.
+	ReadFirst ReadMode = iota
+	// ReadQuorum reads from every replica and returns the value held by a
+	// majority of them, or an *InconsistentError if there is none. A key
+	// missing from a majority is reported as ErrorNoSuchKey. A replica whose
+	// read fails casts no vote; its error is only returned if, without it,
+	// no majority could be reached.
+	ReadQuorum
+)
+
+// ReplicatedStore is a KV that mirrors every write to several replicas, so
+// that each holds a full copy of the data. Set and Delete apply to every
+// replica in order and stop at the first error, which can leave the replicas
+// diverged; GetKeys lists the first replica.
+type ReplicatedStore struct {
+	replicas []KV
+	mode     ReadMode
+}
+
+var _ KV = (*ReplicatedStore)(nil)
+
+// A ReplicatedOption configures a ReplicatedStore.
+type ReplicatedOption func(*ReplicatedStore)
+
+// WithReadMode sets how the ReplicatedStore reads; the default is ReadFirst.
+//
+// ReadQuorum costs one host call per replica for every Get and Exists rather
+// than one, and holds every replica's copy of the value in memory while they
+// are compared. It needs an odd number of replicas for a clean majority: with
+// an even number, an even split between two values is reported as
+// inconsistent even though neither replica set is known to be wrong.
+func WithReadMode(mode ReadMode) ReplicatedOption {
+	return func(r *ReplicatedStore) {
+		r.mode = mode
+	}
+}
+
+// NewReplicatedStore returns a ReplicatedStore over replicas. It panics if
+// replicas is empty.
+func NewReplicatedStore(replicas []KV, opts ...ReplicatedOption) *ReplicatedStore {
+	if len(replicas) == 0 {
+		panic("key_value: NewReplicatedStore called with no replicas")
+	}
+	r := &ReplicatedStore{replicas: replicas}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *ReplicatedStore) Get(key string) ([]byte, error) {
+	if r.mode != ReadQuorum {
+		return r.replicas[0].Get(key)
+	}
+	return r.quorumGet(key)
+}
+
+func (r *ReplicatedStore) Set(key string, value []byte) error {
+	for _, replica := range r.replicas {
+		if err := replica.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ReplicatedStore) Delete(key string) error {
+	for _, replica := range r.replicas {
+		if err := replica.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ReplicatedStore) Exists(key string) (bool, error) {
+	if r.mode != ReadQuorum {
+		return r.replicas[0].Exists(key)
+	}
+	_, err := r.quorumGet(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *ReplicatedStore) GetKeys() ([]string, error) {
+	return r.replicas[0].GetKeys()
+}
+
+func (r *ReplicatedStore) quorumGet(key string) ([]byte, error) {
+	const missing = "\x00missing"
+	values := make([][]byte, len(r.replicas))
+	cast := make([]string, len(r.replicas))
+	votes := make(map[string]int)
+	var firstErr error
+	failed := 0
+	for i, replica := range r.replicas {
+		value, err := replica.Get(key)
+		switch {
+		case errors.Is(err, ErrorNoSuchKey):
+			cast[i] = missing
+		case err != nil:
+			// A failed read is not a vote for any value.
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		default:
+			values[i] = value
+			cast[i] = "\x01" + string(value)
+		}
+		votes[cast[i]]++
+	}
+
+	most := 0
+	for i, vote := range cast {
+		if vote == "" {
+			continue
+		}
+		if votes[vote] > most {
+			most = votes[vote]
+		}
+		if votes[vote]*2 <= len(r.replicas) {
+			continue
+		}
+		if vote == missing {
+			return []byte{}, ErrorNoSuchKey
+		}
+		return values[i], nil
+	}
+	// The failed replicas could have made up a majority, so their error is
+	// the reason there was none.
+	if (most+failed)*2 > len(r.replicas) {
+		return nil, firstErr
+	}
+	return nil, &InconsistentError{Key: key, Values: values}
+}
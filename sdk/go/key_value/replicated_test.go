@@ -0,0 +1,57 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestReplicatedStore(t *testing.T) {
+	kvtest.RoundTripTest(t, key_value.NewReplicatedStore([]key_value.KV{kvtest.NewMemStore(), kvtest.NewMemStore()}))
+}
+
+func TestReplicatedStoreQuorum(t *testing.T) {
+	a, b, c := kvtest.NewMemStore(), kvtest.NewMemStore(), kvtest.NewMemStore()
+	store := key_value.NewReplicatedStore([]key_value.KV{a, b, c}, key_value.WithReadMode(key_value.ReadQuorum))
+
+	if err := store.Set("k", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("k", []byte("two"))
+	if value, err := store.Get("k"); err != nil || string(value) != "one" {
+		t.Fatalf("Get with one diverged replica = %q, %v, want \"one\"", value, err)
+	}
+
+	b.Set("k", []byte("three"))
+	_, err := store.Get("k")
+	var inconsistent *key_value.InconsistentError
+	if !errors.As(err, &inconsistent) || !errors.Is(err, key_value.ErrInconsistent) {
+		t.Fatalf("Get with no majority = %v, want ErrInconsistent", err)
+	}
+	if len(inconsistent.Values) != 3 || string(inconsistent.Values[2]) != "two" {
+		t.Fatalf("InconsistentError.Values = %q", inconsistent.Values)
+	}
+
+	a.Delete("k")
+	b.Delete("k")
+	if _, err := store.Get("k"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Fatalf("Get of key missing from a majority = %v, want ErrorNoSuchKey", err)
+	}
+}
+
+func TestReplicatedStoreQuorumFailedReplica(t *testing.T) {
+	a, b, c := kvtest.NewMemStore(), kvtest.NewMemStore(), kvtest.NewMemStore()
+	store := key_value.NewReplicatedStore([]key_value.KV{a, b, c}, key_value.WithReadMode(key_value.ReadQuorum))
+	store.Set("k", []byte("one"))
+	c.Close()
+
+	if value, err := store.Get("k"); err != nil || string(value) != "one" {
+		t.Fatalf("Get with one failed replica = %q, %v, want \"one\"", value, err)
+	}
+	a.Set("k", []byte("two"))
+	if _, err := store.Get("k"); !errors.Is(err, key_value.ErrorInvalidStore) {
+		t.Fatalf("Get with no majority and a failed replica = %v, want ErrorInvalidStore", err)
+	}
+}
@@ -0,0 +1,97 @@
+package key_value
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ResponseCachePrefix is prepended to a key to form the key ResponseCache
+// keeps the response under.
+const ResponseCachePrefix = "httpcache:"
+
+// ResponseCache keeps HTTP responses, such as those fetched from an upstream
+// service, for an HTTP component to serve again, answering conditional
+// requests with 304 Not Modified.
+//
+// Each response is stored as a JSON envelope of the form
+//
+//	{"status": 200, "header": {"Content-Type": ["text/plain"]}, "body": "<base64>", "etag": "\"…\""}
+//
+// where etag is a strong ETag, the body's SHA-256 hash in hex as ValueHash
+// computes it, set when the response is stored unless the stored header
+// already carries one.
+type ResponseCache struct {
+	store KV
+}
+
+type cachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	ETag   string      `json:"etag"`
+}
+
+// NewResponseCache returns a ResponseCache keeping responses in store.
+func NewResponseCache(store KV) *ResponseCache {
+	return &ResponseCache{store: store}
+}
+
+// Store stores a response under key, replacing any stored before.
+func (c *ResponseCache) Store(key string, status int, header http.Header, body []byte) error {
+	etag := header.Get("ETag")
+	if etag == "" {
+		etag = `"` + sha256Hex(body) + `"`
+	}
+	return SetJSON(c.store, ResponseCachePrefix+key, cachedResponse{
+		Status: status,
+		Header: header,
+		Body:   body,
+		ETag:   etag,
+	})
+}
+
+// Serve writes the response stored under key to w and reports whether there
+// was one; if there was not, nothing is written. If r's If-None-Match header
+// matches the response's ETag, a 304 Not Modified with no body is written
+// instead.
+func (c *ResponseCache) Serve(w http.ResponseWriter, r *http.Request, key string) (bool, error) {
+	var resp cachedResponse
+	err := GetJSON(c.store, ResponseCachePrefix+key, &resp)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("ETag", resp.ETag)
+	if etagMatches(r.Header.Get("If-None-Match"), resp.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true, nil
+	}
+	w.WriteHeader(resp.Status)
+	_, err = w.Write(resp.Body)
+	return true, err
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package key_value_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := key_value.NewResponseCache(kvtest.NewMemStore())
+
+	w := httptest.NewRecorder()
+	if ok, err := cache.Serve(w, httptest.NewRequest("GET", "/", nil), "page"); ok || err != nil {
+		t.Fatalf("Serve before Store = %v, %v", ok, err)
+	}
+
+	header := http.Header{"Content-Type": {"text/plain"}}
+	if err := cache.Store("page", http.StatusOK, header, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	if ok, err := cache.Serve(w, httptest.NewRequest("GET", "/", nil), "page"); !ok || err != nil {
+		t.Fatalf("Serve = %v, %v", ok, err)
+	}
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "hello" || w.Header().Get("Content-Type") != "text/plain" || etag == "" {
+		t.Fatalf("Serve wrote %d %q with header %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"other", `+etag)
+	w = httptest.NewRecorder()
+	if ok, err := cache.Serve(w, r, "page"); !ok || err != nil {
+		t.Fatalf("conditional Serve = %v, %v", ok, err)
+	}
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("conditional Serve wrote %d %q, want 304 with no body", w.Code, w.Body.String())
+	}
+}
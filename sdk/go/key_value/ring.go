@@ -0,0 +1,79 @@
+package key_value
+
+import (
+	"errors"
+	"strconv"
+)
+
+// RingPrefix is prepended to the keys RingBuffer keeps its items in.
+const RingPrefix = "ring:"
+
+// RingBuffer keeps the last items pushed to it, up to a fixed capacity,
+// overwriting the oldest once full, such as for a panel of recent events or
+// errors that must not grow without bound.
+//
+// The number of items ever pushed to the buffer called name is kept with
+// SetInt64's encoding at RingPrefix+name+":head", and the item pushed n-th,
+// counting from zero, at RingPrefix+name+":"+(n mod capacity). The capacity
+// is not stored: every RingBuffer over the same name must use the same one.
+//
+// The head is advanced with Increment, which is not atomic on the host, so
+// the buffer assumes a single writer; concurrent pushes from several
+// instances can claim the same slot, losing an item.
+type RingBuffer struct {
+	store    KV
+	prefix   string
+	capacity int
+}
+
+// NewRingBuffer returns the ring buffer called name, kept in store, holding
+// at most capacity items. It panics if capacity is less than one.
+func NewRingBuffer(store KV, name string, capacity int) *RingBuffer {
+	if capacity < 1 {
+		panic("key_value: NewRingBuffer capacity must be at least 1")
+	}
+	return &RingBuffer{store: store, prefix: RingPrefix + name + ":", capacity: capacity}
+}
+
+// Push adds value as the newest item, overwriting the oldest if the buffer
+// is full.
+func (r *RingBuffer) Push(value []byte) error {
+	n, err := Increment(r.store, r.prefix+"head", 1)
+	if err != nil {
+		return err
+	}
+	return r.store.Set(r.slotKey(n-1), value)
+}
+
+// Items returns the items in the buffer, oldest first. It reads one key per
+// item.
+func (r *RingBuffer) Items() ([][]byte, error) {
+	head, err := GetInt64(r.store, r.prefix+"head")
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	start := head - int64(r.capacity)
+	if start < 0 {
+		start = 0
+	}
+	items := make([][]byte, 0, head-start)
+	for n := start; n < head; n++ {
+		value, err := r.store.Get(r.slotKey(n))
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+func (r *RingBuffer) slotKey(n int64) string {
+	return r.prefix + strconv.FormatInt(n%int64(r.capacity), 10)
+}
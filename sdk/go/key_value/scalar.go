@@ -0,0 +1,262 @@
+package key_value
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ScalarCodec selects how the scalar helpers, such as SetInt64, SetBool,
+// SetFloat64, SetTime and SetDuration, encode their values. Components sharing a store
+// must agree on the codec for the values they exchange.
+type ScalarCodec int
+
+const (
+	// ScalarBinary, the default, stores fixed-size binary encodings: an int64
+	// as 8 bytes big-endian, a bool as the single byte 0x00 or 0x01, a
+	// float64 as its 8-byte big-endian IEEE-754 bits, a time as its Unix
+	// time in nanoseconds encoded like an int64, and a duration as its
+	// nanoseconds encoded like an int64.
+	ScalarBinary ScalarCodec = iota
+	// ScalarText stores text that is easy to read and write from other
+	// tools: an int64 in decimal, a bool as "true" or "false", a float64 in
+	// the shortest decimal or exponent form that round-trips, a time in
+	// RFC 3339 format with nanoseconds, and a duration as a Go duration
+	// string such as "1m30s".
+	ScalarText
+)
+
+// WithScalarCodec returns store wrapped so that the scalar helpers use codec.
+// Other operations pass straight through to store.
+func WithScalarCodec(store KV, codec ScalarCodec) KV {
+	return &scalarOptions{KV: store, codec: codec}
+}
+
+type scalarOptions struct {
+	KV
+	codec ScalarCodec
+}
+
+func (s *scalarOptions) unwrap() KV {
+	return s.KV
+}
+
+func scalarCodecFor(store KV) ScalarCodec {
+	if opts, ok := findOption[*scalarOptions](store); ok {
+		return opts.codec
+	}
+	return ScalarBinary
+}
+
+// SetInt64 stores v at key.
+func SetInt64(store KV, key string, v int64) error {
+	return store.Set(key, scalarCodecFor(store).encodeInt64(v))
+}
+
+// GetInt64 returns the integer stored at key by SetInt64 or Increment.
+func GetInt64(store KV, key string) (int64, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return scalarCodecFor(store).decodeInt64(key, value)
+}
+
+// Increment adds delta to the integer stored at key and returns the result.
+// A missing key is treated as zero.
+//
+// The host has no atomic increment, so Increment reads the current value and
+// writes the new one. Concurrent increments of the same key can be lost.
+func Increment(store KV, key string, delta int64) (int64, error) {
+	defer lockKey(store, key)()
+
+	n, err := GetInt64(store, key)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return 0, err
+	}
+	n += delta
+	if err := SetInt64(store, key, n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// SetTime stores t at key. The monotonic clock reading and location are not
+// kept.
+func SetTime(store KV, key string, t time.Time) error {
+	return store.Set(key, scalarCodecFor(store).encodeTime(t))
+}
+
+// GetTime returns the time stored at key by SetTime, in UTC.
+func GetTime(store KV, key string) (time.Time, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return scalarCodecFor(store).decodeTime(key, value)
+}
+
+// SetDuration stores d at key.
+func SetDuration(store KV, key string, d time.Duration) error {
+	return store.Set(key, scalarCodecFor(store).encodeDuration(d))
+}
+
+// GetDuration returns the duration stored at key by SetDuration.
+func GetDuration(store KV, key string) (time.Duration, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return scalarCodecFor(store).decodeDuration(key, value)
+}
+
+// SetBool stores v at key.
+func SetBool(store KV, key string, v bool) error {
+	return store.Set(key, scalarCodecFor(store).encodeBool(v))
+}
+
+// GetBool returns the bool stored at key by SetBool. An error is returned for
+// any value other than the exact encoding of true or false.
+func GetBool(store KV, key string) (bool, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return scalarCodecFor(store).decodeBool(key, value)
+}
+
+// SetFloat64 stores v at key.
+func SetFloat64(store KV, key string, v float64) error {
+	return store.Set(key, scalarCodecFor(store).encodeFloat64(v))
+}
+
+// GetFloat64 returns the float stored at key by SetFloat64.
+func GetFloat64(store KV, key string) (float64, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	return scalarCodecFor(store).decodeFloat64(key, value)
+}
+
+func (c ScalarCodec) encodeInt64(v int64) []byte {
+	if c == ScalarText {
+		return strconv.AppendInt(nil, v, 10)
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+func (c ScalarCodec) decodeInt64(key string, value []byte) (int64, error) {
+	if c == ScalarText {
+		n, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value at %q is not an int64: %w", key, err)
+		}
+		return n, nil
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("value at %q is %d bytes, want 8 for an int64", key, len(value))
+	}
+	return int64(binary.BigEndian.Uint64(value)), nil
+}
+
+func (c ScalarCodec) encodeBool(v bool) []byte {
+	if c == ScalarText {
+		return strconv.AppendBool(nil, v)
+	}
+	if v {
+		return []byte{0x01}
+	}
+	return []byte{0x00}
+}
+
+func (c ScalarCodec) decodeBool(key string, value []byte) (bool, error) {
+	if c == ScalarText {
+		switch string(value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	} else if len(value) == 1 {
+		switch value[0] {
+		case 0x00:
+			return false, nil
+		case 0x01:
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("value at %q is not a bool: %q", key, value)
+}
+
+func (c ScalarCodec) encodeFloat64(v float64) []byte {
+	if c == ScalarText {
+		return strconv.AppendFloat(nil, v, 'g', -1, 64)
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return b
+}
+
+func (c ScalarCodec) decodeFloat64(key string, value []byte) (float64, error) {
+	if c == ScalarText {
+		f, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value at %q is not a float64: %w", key, err)
+		}
+		return f, nil
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("value at %q is %d bytes, want 8 for a float64", key, len(value))
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(value)), nil
+}
+
+func (c ScalarCodec) encodeTime(t time.Time) []byte {
+	if c == ScalarText {
+		return t.UTC().AppendFormat(nil, time.RFC3339Nano)
+	}
+	return c.encodeInt64(t.UnixNano())
+}
+
+func (c ScalarCodec) decodeTime(key string, value []byte) (time.Time, error) {
+	if c == ScalarText {
+		t, err := time.Parse(time.RFC3339Nano, string(value))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("value at %q is not a time: %w", key, err)
+		}
+		return t.UTC(), nil
+	}
+	n, err := c.decodeInt64(key, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, n).UTC(), nil
+}
+
+func (c ScalarCodec) encodeDuration(d time.Duration) []byte {
+	if c == ScalarText {
+		return []byte(d.String())
+	}
+	return c.encodeInt64(int64(d))
+}
+
+func (c ScalarCodec) decodeDuration(key string, value []byte) (time.Duration, error) {
+	if c == ScalarText {
+		d, err := time.ParseDuration(string(value))
+		if err != nil {
+			return 0, fmt.Errorf("value at %q is not a duration: %w", key, err)
+		}
+		return d, nil
+	}
+	n, err := c.decodeInt64(key, value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n), nil
+}
@@ -0,0 +1,227 @@
+package key_value
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+// Scan calls fn for each key in store that starts with prefix, along with its
+// value. Iteration stops early without error when fn returns false, and stops
+// with fn's error when it returns one. Values are read one at a time as the
+// iteration reaches them; keys deleted after the listing are skipped. Keys
+// are visited in the order the store lists them.
+func Scan(store KV, prefix string, fn func(key string, value []byte) (bool, error)) error {
+	keys, err := keysWithPrefix(store, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		more, err := fn(key, value)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// KeyValue is one entry of a store, as sent by Stream.
+type KeyValue struct {
+	Key   string
+	Value []byte
+}
+
+// Stream sends every entry of store on the returned channel, for iterating a
+// large store from a goroutine doing concurrent work; Scan is the callback
+// equivalent. The keys are listed up front and each value is read as the
+// iteration reaches it, one at a time, so at most one entry is read ahead
+// of the receiver. Keys deleted after the listing are skipped.
+//
+// The entry channel is unbuffered and is closed when every entry has been
+// sent, when an error occurs or when ctx is done. The error channel is
+// buffered: at most one error, ctx.Err() if ctx was done first, is sent on
+// it before it is closed, so it can be read once the entry channel closes.
+func Stream(ctx context.Context, store KV) (<-chan KeyValue, <-chan error) {
+	entries := make(chan KeyValue)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(entries)
+
+		err := Scan(store, "", func(key string, value []byte) (bool, error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			select {
+			case entries <- KeyValue{Key: key, Value: value}:
+				return true, nil
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return entries, errs
+}
+
+// HasAnyWithPrefix reports whether store has at least one key starting with
+// prefix. The host returns the full key list in one call, so this still
+// lists every key, but it stops looking at the first match and reads no
+// values.
+func HasAnyWithPrefix(store KV, prefix string) (bool, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PrefixSize returns how many keys start with prefix and the total size of
+// their values in bytes, not counting the keys themselves, such as for
+// tracking each tenant's usage of a shared store. It lists every key in the
+// store and reads every value under the prefix, so it costs a host call per
+// key; the totals are a point-in-time view that concurrent writes can change
+// before it returns.
+func PrefixSize(store KV, prefix string) (keys int, bytes int64, err error) {
+	err = Scan(store, prefix, func(key string, value []byte) (bool, error) {
+		keys++
+		bytes += int64(len(value))
+		return true, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return keys, bytes, nil
+}
+
+// Glob returns the keys of store matching the shell-style pattern, in the
+// order the store lists them, for selecting keys more precisely than by
+// prefix, as in Glob(store, "session:*:temp"). Matching follows path.Match:
+// "*" matches any run of characters other than "/", "?" matches any one
+// character other than "/", "[...]" matches a character class and "\\"
+// escapes the next character; the whole key must match. path.ErrBadPattern
+// is returned for a malformed pattern. Glob lists every key in the store and
+// matches them client-side.
+func Glob(store KV, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	keys, err := store.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, key := range keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			matched = append(matched, key)
+		}
+	}
+	return matched, nil
+}
+
+// DeleteWhere deletes every entry of store for which fn returns true and
+// returns how many were deleted. It reads every key and value in the store,
+// so it costs one listing plus a read per key. Deletes are issued one at a
+// time as matches are found and are not atomic: an error from fn or from the
+// store stops the sweep, leaving the entries deleted so far deleted, and the
+// count reports them.
+func DeleteWhere(store KV, fn func(key string, value []byte) (bool, error)) (int, error) {
+	deleted := 0
+	err := Scan(store, "", func(key string, value []byte) (bool, error) {
+		match, err := fn(key, value)
+		if err != nil || !match {
+			return true, err
+		}
+		if err := store.Delete(key); err != nil {
+			return false, err
+		}
+		deleted++
+		return true, nil
+	})
+	return deleted, err
+}
+
+// DeletePrefixBatch deletes up to limit keys starting with prefix and reports
+// whether any keys with the prefix remain, so a large prefix can be cleared
+// over several invocations that each fit within a request's time budget. It
+// is resumable: call it again until more is false. Each call lists every key
+// in the store; deleted counts the keys deleted before any error. A negative
+// limit is an error.
+func DeletePrefixBatch(store KV, prefix string, limit int) (deleted int, more bool, err error) {
+	if limit < 0 {
+		return 0, false, errors.New("delete batch limit must not be negative")
+	}
+	keys, err := keysWithPrefix(store, prefix)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, key := range keys {
+		if deleted == limit {
+			return deleted, true, nil
+		}
+		if err := store.Delete(key); err != nil {
+			return deleted, true, err
+		}
+		deleted++
+	}
+	return deleted, false, nil
+}
+
+// Map applies fn to every entry of store and returns the results, stopping at
+// the first error. Results are in the order the store lists its keys, which
+// is unspecified for the host store.
+func Map[T any](store KV, fn func(key string, value []byte) (T, error)) ([]T, error) {
+	var results []T
+	err := Scan(store, "", func(key string, value []byte) (bool, error) {
+		result, err := fn(key, value)
+		if err != nil {
+			return false, err
+		}
+		results = append(results, result)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Filter returns the keys of the entries of store for which fn returns true,
+// in the order the store lists them. Like DeleteWhere it reads every value in
+// the store, so it suits small stores; an error from fn stops the search.
+func Filter(store KV, fn func(key string, value []byte) (bool, error)) ([]string, error) {
+	var keys []string
+	err := Scan(store, "", func(key string, value []byte) (bool, error) {
+		match, err := fn(key, value)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			keys = append(keys, key)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
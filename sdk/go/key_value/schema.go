@@ -0,0 +1,34 @@
+package key_value
+
+import "errors"
+
+// SchemaVersionKey is the key EnsureSchemaVersion records the store's schema
+// version under.
+const SchemaVersionKey = "__schema_version"
+
+// EnsureSchemaVersion upgrades the data in store to schema version current,
+// for running at component startup before the data is used. If the version
+// recorded at SchemaVersionKey is below current, migrate is called with it,
+// and the version is recorded as current once migrate succeeds. A store with
+// no recorded version is at version 0. If migrate fails, its error is
+// returned and the version is left unchanged.
+//
+// Nothing stops two instances starting together from both migrating, and a
+// crash after migrate but before the version is recorded runs migrate again
+// on the next start, so migrate must cope with data it has already partly or
+// wholly upgraded.
+func EnsureSchemaVersion(store KV, current int, migrate func(from int) error) error {
+	defer lockKey(store, SchemaVersionKey)()
+
+	version, err := GetInt64(store, SchemaVersionKey)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return err
+	}
+	if version >= int64(current) {
+		return nil
+	}
+	if err := migrate(int(version)); err != nil {
+		return err
+	}
+	return SetInt64(store, SchemaVersionKey, int64(current))
+}
@@ -0,0 +1,42 @@
+package key_value
+
+import "fmt"
+
+// SequencePrefix is prepended to a sequence name to form the key its counter
+// is stored under.
+const SequencePrefix = "seq:"
+
+// Sequence mints monotonically increasing ids, such as order numbers, from a
+// counter kept in a store.
+//
+// Ids are only unique within a single backing store. The counter is advanced
+// with Increment, which is not atomic on the host, so component instances
+// drawing from the same sequence concurrently can be handed the same id.
+type Sequence struct {
+	store KV
+	key   string
+}
+
+// NewSequence returns the sequence called name, kept in store. A sequence
+// that has not been used yet starts at 1.
+func NewSequence(store KV, name string) *Sequence {
+	return &Sequence{store: store, key: SequencePrefix + name}
+}
+
+// Next returns the next id in the sequence.
+func (s *Sequence) Next() (int64, error) {
+	return Increment(s.store, s.key, 1)
+}
+
+// NextBatch reserves n consecutive ids with a single update and returns the
+// first of them.
+func (s *Sequence) NextBatch(n int) (int64, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("invalid sequence batch size %d", n)
+	}
+	end, err := Increment(s.store, s.key, int64(n))
+	if err != nil {
+		return 0, err
+	}
+	return end - int64(n) + 1, nil
+}
@@ -0,0 +1,109 @@
+package key_value
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// SessionPrefix is prepended to a session id to form the key the session is
+// stored under.
+const SessionPrefix = "session:"
+
+// The number of attempts Create makes to find an unused session id.
+const sessionCreateAttempts = 5
+
+// SessionStore keeps HTTP session data in a key-value store. Each session is
+// stored as a JSON document at SessionPrefix+id.
+type SessionStore struct {
+	store KV
+	ttl   time.Duration
+}
+
+// NewSessionStore returns a session store backed by store. Sessions expire
+// after ttl has passed since they were last saved; a ttl of zero keeps them
+// until they are destroyed. Expired sessions are deleted when next loaded.
+func NewSessionStore(store KV, ttl time.Duration) *SessionStore {
+	return &SessionStore{store: store, ttl: ttl}
+}
+
+type session struct {
+	Expires int64                  `json:"expires,omitempty"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+// Create starts a new, empty session and returns its id. Ids are 128 bits
+// read from crypto/rand and are checked against existing sessions before use.
+func (s *SessionStore) Create() (string, error) {
+	for i := 0; i < sessionCreateAttempts; i++ {
+		id, err := randomID()
+		if err != nil {
+			return "", err
+		}
+		exists, err := s.store.Exists(SessionPrefix + id)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			continue
+		}
+		if err := s.Save(id, map[string]interface{}{}); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	return "", errors.New("failed to generate a unique session id")
+}
+
+// Load returns the data of the session with the given id. ErrorNoSuchKey is
+// returned if the session does not exist or has expired.
+func (s *SessionStore) Load(id string) (map[string]interface{}, error) {
+	value, err := s.store.Get(SessionPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+
+	var sess session
+	if err := json.Unmarshal(value, &sess); err != nil {
+		return nil, err
+	}
+	if sess.Expires != 0 && time.Now().UnixNano() >= sess.Expires {
+		if err := s.Destroy(id); err != nil && !errors.Is(err, ErrorNoSuchKey) {
+			return nil, err
+		}
+		return nil, ErrorNoSuchKey
+	}
+	if sess.Data == nil {
+		sess.Data = map[string]interface{}{}
+	}
+	return sess.Data, nil
+}
+
+// Save replaces the data of the session with the given id and, when the store
+// has a ttl, extends the session's lifetime.
+func (s *SessionStore) Save(id string, data map[string]interface{}) error {
+	sess := session{Data: data}
+	if s.ttl > 0 {
+		sess.Expires = time.Now().Add(s.ttl).UnixNano()
+	}
+	value, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(SessionPrefix+id, value)
+}
+
+// Destroy deletes the session with the given id.
+func (s *SessionStore) Destroy(id string) error {
+	return s.store.Delete(SessionPrefix + id)
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,79 @@
+package key_value
+
+import "hash/fnv"
+
+// ShardedStore is a KV that spreads its keys across several stores, choosing
+// the store for each key by hashing it. It can be used to distribute load or
+// data size across multiple backends while treating them as one store.
+//
+// The number of shards is fixed at construction. Building a ShardedStore over
+// a different number of stores, or the same stores in a different order,
+// sends most keys to a different shard, so existing data must be moved when
+// the shard set changes.
+type ShardedStore struct {
+	shards []KV
+}
+
+var _ KV = (*ShardedStore)(nil)
+
+// NewShardedStore returns a ShardedStore over shards. Operations on a
+// ShardedStore with no shards return ErrNoStores, as for a ConsistentStore.
+func NewShardedStore(shards []KV) *ShardedStore {
+	return &ShardedStore{shards: shards}
+}
+
+// Shard returns the store that holds key, or ErrNoStores if there are none.
+func (s *ShardedStore) Shard(key string) (KV, error) {
+	if len(s.shards) == 0 {
+		return nil, ErrNoStores
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))], nil
+}
+
+func (s *ShardedStore) Get(key string) ([]byte, error) {
+	shard, err := s.Shard(key)
+	if err != nil {
+		return []byte{}, err
+	}
+	return shard.Get(key)
+}
+
+func (s *ShardedStore) Set(key string, value []byte) error {
+	shard, err := s.Shard(key)
+	if err != nil {
+		return err
+	}
+	return shard.Set(key, value)
+}
+
+func (s *ShardedStore) Delete(key string) error {
+	shard, err := s.Shard(key)
+	if err != nil {
+		return err
+	}
+	return shard.Delete(key)
+}
+
+func (s *ShardedStore) Exists(key string) (bool, error) {
+	shard, err := s.Shard(key)
+	if err != nil {
+		return false, err
+	}
+	return shard.Exists(key)
+}
+
+// GetKeys returns the keys of every shard. It lists each shard in turn, so
+// the result is not a consistent snapshot of the whole store.
+func (s *ShardedStore) GetKeys() ([]string, error) {
+	var keys []string
+	for _, shard := range s.shards {
+		shardKeys, err := shard.GetKeys()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, shardKeys...)
+	}
+	return keys, nil
+}
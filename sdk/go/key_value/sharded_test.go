@@ -0,0 +1,61 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestShardedStore(t *testing.T) {
+	shards := []key_value.KV{kvtest.NewMemStore(), kvtest.NewMemStore(), kvtest.NewMemStore()}
+	store := key_value.NewShardedStore(shards)
+	kvtest.RoundTripTest(t, store)
+
+	for i := 0; i < 100; i++ {
+		key := string(rune('a'+i%26)) + string(rune('0'+i/26))
+		if err := store.Set(key, []byte(key)); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+		shard, err := store.Shard(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := shard.Exists(key); err != nil || !ok {
+			t.Fatalf("key %q not in its shard: %v, %v", key, ok, err)
+		}
+	}
+	for i, shard := range shards {
+		keys, err := shard.GetKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) == 0 {
+			t.Errorf("shard %d holds no keys", i)
+		}
+	}
+	keys, err := store.GetKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 100 {
+		t.Errorf("GetKeys returned %d keys, want 100", len(keys))
+	}
+}
+
+func TestShardedStoreNoShards(t *testing.T) {
+	store := key_value.NewShardedStore(nil)
+	if err := store.Set("k", nil); !errors.Is(err, key_value.ErrNoStores) {
+		t.Errorf("Set with no shards: %v, want ErrNoStores", err)
+	}
+	if _, err := store.Get("k"); !errors.Is(err, key_value.ErrNoStores) {
+		t.Errorf("Get with no shards: %v, want ErrNoStores", err)
+	}
+	if _, err := store.Shard("k"); !errors.Is(err, key_value.ErrNoStores) {
+		t.Errorf("Shard with no shards: %v, want ErrNoStores", err)
+	}
+	if keys, err := store.GetKeys(); err != nil || len(keys) != 0 {
+		t.Errorf("GetKeys with no shards = %v, %v, want none", keys, err)
+	}
+}
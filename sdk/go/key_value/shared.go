@@ -0,0 +1,50 @@
+package key_value
+
+import "sync"
+
+var shared = struct {
+	sync.Mutex
+	stores map[string]*sharedStore
+}{stores: map[string]*sharedStore{}}
+
+type sharedStore struct {
+	store Store
+	refs  int
+}
+
+// Shared returns a handle to the named store that is shared with every other
+// caller of Shared, along with a function that releases the reference. The
+// store is opened on the first request and closed when the last reference is
+// released. Calling the release function more than once has no effect.
+//
+// Handles returned by Shared must not be passed to Close.
+func Shared(name string) (Store, func(), error) {
+	shared.Lock()
+	defer shared.Unlock()
+
+	s, ok := shared.stores[name]
+	if !ok {
+		store, err := Open(name)
+		if err != nil {
+			return 0xFFFF_FFFF, func() {}, err
+		}
+		s = &sharedStore{store: store}
+		shared.stores[name] = s
+	}
+	s.refs++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			shared.Lock()
+			defer shared.Unlock()
+
+			s.refs--
+			if s.refs == 0 {
+				Close(s.store)
+				delete(shared.stores, name)
+			}
+		})
+	}
+	return s.store, release, nil
+}
@@ -0,0 +1,165 @@
+package key_value
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// tombstoneMagic marks a value deleted through WithSoftDelete. It is followed
+// by the deletion time as 8 bytes of big-endian Unix nanoseconds, and then
+// the value the key held.
+const (
+	tombstoneMagic     = "\x00tombstone\x01"
+	tombstoneHeaderLen = len(tombstoneMagic) + 8
+)
+
+// WithSoftDelete returns store wrapped so that Delete keeps a key's value
+// behind a tombstone instead of removing it, so deletions can be undone with
+// Undelete until Purge removes them. Through the returned KV, Get, Exists and
+// GetKeys treat tombstoned keys as absent, and Set replaces a tombstone like
+// any other value.
+//
+// A tombstoned key keeps its whole value plus a 19-byte header, so deleted
+// data takes up space until it is purged. Other handles on the store see the
+// tombstones as ordinary values. Delete reads the value and then writes the
+// tombstone without taking a key lock of its own, so that it can be called
+// from helpers such as GetAndDelete that already hold one.
+func WithSoftDelete(store KV) KV {
+	return &softDelete{KV: store}
+}
+
+type softDelete struct {
+	KV
+}
+
+func (s *softDelete) unwrap() KV {
+	return s.KV
+}
+
+func (s *softDelete) Get(key string) ([]byte, error) {
+	value, err := s.KV.Get(key)
+	if err != nil {
+		return value, err
+	}
+	if _, _, ok := splitTombstone(value); ok {
+		return []byte{}, ErrorNoSuchKey
+	}
+	return value, nil
+}
+
+func (s *softDelete) Exists(key string) (bool, error) {
+	_, err := s.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *softDelete) Delete(key string) error {
+	value, err := s.KV.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, _, ok := splitTombstone(value); ok {
+		return nil
+	}
+	buf := make([]byte, tombstoneHeaderLen+len(value))
+	copy(buf, tombstoneMagic)
+	binary.BigEndian.PutUint64(buf[len(tombstoneMagic):], uint64(time.Now().UnixNano()))
+	copy(buf[tombstoneHeaderLen:], value)
+	return s.KV.Set(key, buf)
+}
+
+// GetKeys lists the keys of the store that are not tombstoned. It reads every
+// value to tell.
+func (s *softDelete) GetKeys() ([]string, error) {
+	keys, err := s.KV.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+	live := keys[:0]
+	for _, key := range keys {
+		ok, err := s.Exists(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			live = append(live, key)
+		}
+	}
+	return live, nil
+}
+
+// Undelete restores the value of key if it was deleted through
+// WithSoftDelete and has not been purged, and reports whether it did.
+func Undelete(store KV, key string) (bool, error) {
+	base := softDeleteBase(store)
+	defer lockKey(base, key)()
+
+	value, err := base.Get(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	original, _, ok := splitTombstone(value)
+	if !ok {
+		return false, nil
+	}
+	return true, base.Set(key, original)
+}
+
+// Purge removes the keys deleted through WithSoftDelete more than olderThan
+// ago, after which they can no longer be undeleted, and returns how many it
+// removed. It reads every value in the store.
+func Purge(store KV, olderThan time.Duration) (int, error) {
+	base := softDeleteBase(store)
+	keys, err := base.GetKeys()
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	purged := 0
+	for _, key := range keys {
+		value, err := base.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		if _, deleted, ok := splitTombstone(value); !ok || deleted.After(cutoff) {
+			continue
+		}
+		ok, err := DeleteIf(base, key, value)
+		if err != nil {
+			return purged, err
+		}
+		if ok {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// softDeleteBase returns the store beneath a WithSoftDelete option, so that
+// tombstones can be seen, or store itself if there is none.
+func softDeleteBase(store KV) KV {
+	if s, ok := findOption[*softDelete](store); ok {
+		return s.KV
+	}
+	return store
+}
+
+func splitTombstone(value []byte) ([]byte, time.Time, bool) {
+	if len(value) < tombstoneHeaderLen || string(value[:len(tombstoneMagic)]) != tombstoneMagic {
+		return value, time.Time{}, false
+	}
+	deleted := int64(binary.BigEndian.Uint64(value[len(tombstoneMagic):tombstoneHeaderLen]))
+	return value[tombstoneHeaderLen:], time.Unix(0, deleted), true
+}
@@ -0,0 +1,88 @@
+package key_value_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestSoftDelete(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	store := key_value.WithSoftDelete(backing)
+	store.Set("a", []byte("1"))
+	store.Set("b", []byte("2"))
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("a"); !errors.Is(err, key_value.ErrorNoSuchKey) {
+		t.Errorf("Get of deleted key: %v, want ErrorNoSuchKey", err)
+	}
+	if ok, err := store.Exists("a"); err != nil || ok {
+		t.Errorf("Exists of deleted key = %v, %v, want false", ok, err)
+	}
+	if keys, err := store.GetKeys(); err != nil || !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Errorf("GetKeys = %q, %v, want [b]", keys, err)
+	}
+	if ok, _ := backing.Exists("a"); !ok {
+		t.Error("Delete removed the key from the backing store")
+	}
+
+	if ok, err := key_value.Undelete(store, "a"); err != nil || !ok {
+		t.Fatalf("Undelete = %v, %v, want true", ok, err)
+	}
+	if value, err := store.Get("a"); err != nil || string(value) != "1" {
+		t.Errorf("Get after Undelete = %q, %v, want 1", value, err)
+	}
+	if ok, err := key_value.Undelete(store, "b"); err != nil || ok {
+		t.Errorf("Undelete of live key = %v, %v, want false", ok, err)
+	}
+
+	store.Delete("b")
+	if n, err := key_value.Purge(store, time.Hour); err != nil || n != 0 {
+		t.Errorf("Purge(hour) = %d, %v, want 0", n, err)
+	}
+	if n, err := key_value.Purge(store, 0); err != nil || n != 1 {
+		t.Errorf("Purge(0) = %d, %v, want 1", n, err)
+	}
+	if ok, err := key_value.Undelete(store, "b"); err != nil || ok {
+		t.Errorf("Undelete after Purge = %v, %v, want false", ok, err)
+	}
+}
+
+func TestSoftDeleteOverKeyLocking(t *testing.T) {
+	locked := key_value.WithKeyLocking(kvtest.NewMemStore())
+	stores := map[string]key_value.KV{
+		"WithKeyLocking": key_value.WithSoftDelete(locked),
+		"WithHistory":    key_value.WithSoftDelete(key_value.WithHistory(locked, 2)),
+	}
+	for name, store := range stores {
+		done := make(chan error, 1)
+		go func() {
+			store.Set("k", []byte("v"))
+			if _, _, err := key_value.GetAndDelete(store, "k"); err != nil {
+				done <- err
+				return
+			}
+			store.Set("k", []byte("v"))
+			if err := store.Delete("k"); err != nil {
+				done <- err
+				return
+			}
+			_, err := key_value.Undelete(store, "k")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: soft delete deadlocked", name)
+		}
+	}
+}
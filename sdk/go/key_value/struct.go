@@ -0,0 +1,199 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SetStruct stores each exported field of the struct v, or pointer to one,
+// under its own key, prefix+"."+name, so that fields can be read and updated
+// individually by other tools. A field's name is taken from its `kv` tag if
+// it has one, otherwise the Go field name is used; a tag of "-" skips the
+// field. Nested structs, except time.Time, are flattened the same way with
+// further dotted keys.
+//
+// Strings and byte slices are stored as they are. Integers, bools, floats
+// and times are encoded like SetInt64, SetBool, SetFloat64 and SetTime,
+// honoring WithScalarCodec. Other types are stored as JSON.
+func SetStruct(store KV, prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("SetStruct: got %T, want a struct", v)
+	}
+	return setStructFields(store, scalarCodecFor(store), prefix, rv)
+}
+
+// GetStruct fills the fields of the struct pointed to by v from the keys
+// written by SetStruct with the same prefix. Fields whose key does not exist
+// are left unchanged.
+func GetStruct(store KV, prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("GetStruct: got %T, want a pointer to a struct", v)
+	}
+	return getStructFields(store, scalarCodecFor(store), prefix, rv.Elem())
+}
+
+// structFieldKey returns the key of field f under prefix, or false if the
+// field is not stored.
+func structFieldKey(prefix string, f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	name := f.Name
+	if tag, ok := f.Tag.Lookup("kv"); ok {
+		if tag == "-" {
+			return "", false
+		}
+		if tag != "" {
+			name = tag
+		}
+	}
+	if prefix == "" {
+		return name, true
+	}
+	return prefix + "." + name, true
+}
+
+func setStructFields(store KV, codec ScalarCodec, prefix string, rv reflect.Value) error {
+	for i := 0; i < rv.NumField(); i++ {
+		key, ok := structFieldKey(prefix, rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+		if field.Kind() == reflect.Struct && field.Type() != timeType {
+			if err := setStructFields(store, codec, key, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := encodeField(codec, field)
+		if err != nil {
+			return fmt.Errorf("encoding %q: %w", key, err)
+		}
+		if err := store.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getStructFields(store KV, codec ScalarCodec, prefix string, rv reflect.Value) error {
+	for i := 0; i < rv.NumField(); i++ {
+		key, ok := structFieldKey(prefix, rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+		if field.Kind() == reflect.Struct && field.Type() != timeType {
+			if err := getStructFields(store, codec, key, field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := decodeField(codec, key, value, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeField(codec ScalarCodec, field reflect.Value) ([]byte, error) {
+	if field.Type() == timeType {
+		return codec.encodeTime(field.Interface().(time.Time)), nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		return []byte(field.String()), nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			return append([]byte{}, field.Bytes()...), nil
+		}
+	case reflect.Bool:
+		return codec.encodeBool(field.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return codec.encodeInt64(field.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return codec.encodeInt64(int64(field.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return codec.encodeFloat64(field.Float()), nil
+	}
+	return json.Marshal(field.Interface())
+}
+
+func decodeField(codec ScalarCodec, key string, value []byte, field reflect.Value) error {
+	if field.Type() == timeType {
+		t, err := codec.decodeTime(key, value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(string(value))
+		return nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes(value)
+			return nil
+		}
+	case reflect.Bool:
+		b, err := codec.decodeBool(key, value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := codec.decodeInt64(key, value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("value at %q overflows %s", key, field.Type())
+		}
+		field.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := codec.decodeInt64(key, value)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("value at %q overflows %s", key, field.Type())
+		}
+		field.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := codec.decodeFloat64(key, value)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+		return nil
+	}
+	if err := json.Unmarshal(value, field.Addr().Interface()); err != nil {
+		return fmt.Errorf("decoding %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package key_value_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+type serverConfig struct {
+	Host    string `kv:"host"`
+	Port    uint16 `kv:"port"`
+	Debug   bool
+	Ratio   float64
+	Started time.Time
+	Tags    []string
+	Secret  string `kv:"-"`
+	TLS     struct {
+		Cert []byte `kv:"cert"`
+	} `kv:"tls"`
+	internal int
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	store := kvtest.NewMemStore()
+	in := serverConfig{
+		Host:    "example.com",
+		Port:    8080,
+		Debug:   true,
+		Ratio:   0.5,
+		Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags:    []string{"a", "b"},
+		Secret:  "hidden",
+	}
+	in.TLS.Cert = []byte("pem")
+	if err := key_value.SetStruct(store, "server", &in); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, _ := store.GetKeys()
+	want := []string{"server.Debug", "server.Ratio", "server.Started", "server.Tags", "server.host", "server.port", "server.tls.cert"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+
+	out := serverConfig{Secret: "kept"}
+	if err := key_value.GetStruct(store, "server", &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Secret = "kept"
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("GetStruct = %+v, want %+v", out, in)
+	}
+}
+
+func TestGetStructLeavesMissingFields(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("server.host", []byte("example.com"))
+
+	out := serverConfig{Port: 443}
+	if err := key_value.GetStruct(store, "server", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Host != "example.com" || out.Port != 443 {
+		t.Errorf("GetStruct = %+v, want host set and port unchanged", out)
+	}
+}
+
+func TestStructErrors(t *testing.T) {
+	store := kvtest.NewMemStore()
+	if err := key_value.SetStruct(store, "x", 5); err == nil {
+		t.Error("SetStruct of an int succeeded")
+	}
+	var cfg serverConfig
+	if err := key_value.GetStruct(store, "x", cfg); err == nil {
+		t.Error("GetStruct into a non-pointer succeeded")
+	}
+
+	key_value.SetInt64(store, "server.port", 1<<20)
+	if err := key_value.GetStruct(store, "server", &cfg); err == nil {
+		t.Error("GetStruct of an overflowing port succeeded")
+	}
+	key_value.SetInt64(store, "server.port", 80)
+	store.Set("server.Tags", []byte("not json"))
+	if err := key_value.GetStruct(store, "server", &cfg); err == nil {
+		t.Error("GetStruct of malformed JSON succeeded")
+	}
+}
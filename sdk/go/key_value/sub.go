@@ -0,0 +1,68 @@
+package key_value
+
+import "strings"
+
+// Sub returns a view of store confined to namespace, such as one tenant's
+// data in a store shared by many. Keys passed to the view are stored at
+// namespace+":"+key, and GetKeys lists only the keys in the namespace, with
+// the namespace removed, so Clear and the other helpers taking a KV also stay
+// within it.
+//
+// Sub composes: Sub(Sub(store, "a"), "b") stores keys at "a:b:"+key. The
+// With* options of this package see through a Sub only when applied on top
+// of it, as in WithKeyLocking(Sub(store, tenant)); options applied to store
+// beneath the Sub are not seen by helpers given the view.
+func Sub(store KV, namespace string) KV {
+	prefix := namespace + ":"
+	if s, ok := store.(*subStore); ok {
+		return &subStore{store: s.store, prefix: s.prefix + prefix}
+	}
+	return &subStore{store: store, prefix: prefix}
+}
+
+type subStore struct {
+	store  KV
+	prefix string
+}
+
+func (s *subStore) Get(key string) ([]byte, error) {
+	return s.store.Get(s.prefix + key)
+}
+
+func (s *subStore) Set(key string, value []byte) error {
+	return s.store.Set(s.prefix+key, value)
+}
+
+func (s *subStore) Delete(key string) error {
+	return s.store.Delete(s.prefix + key)
+}
+
+func (s *subStore) Exists(key string) (bool, error) {
+	return s.store.Exists(s.prefix + key)
+}
+
+func (s *subStore) GetKeys() ([]string, error) {
+	keys, err := keysWithPrefix(s.store, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, s.prefix)
+	}
+	return keys, nil
+}
+
+// Clear deletes every key store lists, and returns how many it deleted. Given
+// a Sub, it deletes only the keys in that namespace.
+func Clear(store KV) (int, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		if err := store.Delete(key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,136 @@
+package key_value
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeSeriesPrefix is prepended to the keys TimeSeries stores its buckets in.
+const TimeSeriesPrefix = "ts:"
+
+// Point is a single recorded value of a metric.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// TimeSeries records metric values into time buckets of a fixed width.
+//
+// The points of a metric that fall into the same bucket are stored together
+// as a JSON array at TimeSeriesPrefix+metric+":"+n, where n is the bucket's
+// start time in Unix nanoseconds divided by the bucket width. Each point is
+// an object {"t": <Unix nanoseconds>, "v": <value>}.
+//
+// Narrow buckets make Record cheaper, since each write rewrites the whole
+// bucket, but make Range read more keys.
+type TimeSeries struct {
+	store  KV
+	bucket time.Duration
+}
+
+// NewTimeSeries returns a time series backed by store that groups points into
+// buckets of width bucket, such as time.Minute. It panics if bucket is not
+// positive.
+func NewTimeSeries(store KV, bucket time.Duration) *TimeSeries {
+	if bucket <= 0 {
+		panic("key_value: NewTimeSeries called with bucket <= 0")
+	}
+	return &TimeSeries{store: store, bucket: bucket}
+}
+
+type storedPoint struct {
+	T int64   `json:"t"`
+	V float64 `json:"v"`
+}
+
+// Record adds a point for metric at time t. Recording reads and rewrites the
+// point's bucket, so concurrent writers to the same bucket can lose points.
+func (ts *TimeSeries) Record(metric string, t time.Time, value float64) error {
+	key := ts.key(metric, ts.bucketOf(t))
+	defer lockKey(ts.store, key)()
+
+	points, err := ts.load(key)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return err
+	}
+	points = append(points, storedPoint{T: t.UnixNano(), V: value})
+
+	raw, err := json.Marshal(points)
+	if err != nil {
+		return err
+	}
+	return ts.store.Set(key, raw)
+}
+
+// Range returns the points of metric recorded between from and to, inclusive,
+// ordered by time. It lists every key in the store to find the metric's
+// buckets, then reads each bucket that overlaps the window.
+func (ts *TimeSeries) Range(metric string, from, to time.Time) ([]Point, error) {
+	prefix := TimeSeriesPrefix + metric + ":"
+	keys, err := keysWithPrefix(ts.store, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	first := ts.bucketOf(from)
+	last := ts.bucketOf(to)
+	var result []Point
+	for _, key := range keys {
+		n, err := strconv.ParseInt(strings.TrimPrefix(key, prefix), 10, 64)
+		if err != nil || n < first || n > last {
+			// Not a bucket of this metric, or outside the window.
+			continue
+		}
+		points, err := ts.load(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range points {
+			t := time.Unix(0, p.T)
+			if t.Before(from) || t.After(to) {
+				continue
+			}
+			result = append(result, Point{Time: t, Value: p.V})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result, nil
+}
+
+// bucketOf returns the number of the bucket holding t. The division rounds
+// down rather than toward zero, so that bucket 0 is no wider than the others
+// and times before 1970 fall into buckets of their own.
+func (ts *TimeSeries) bucketOf(t time.Time) int64 {
+	n, width := t.UnixNano(), int64(ts.bucket)
+	if n < 0 && n%width != 0 {
+		return n/width - 1
+	}
+	return n / width
+}
+
+func (ts *TimeSeries) key(metric string, bucket int64) string {
+	return TimeSeriesPrefix + metric + ":" + strconv.FormatInt(bucket, 10)
+}
+
+func (ts *TimeSeries) load(key string) ([]storedPoint, error) {
+	raw, err := ts.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []storedPoint
+	if err := json.Unmarshal(raw, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
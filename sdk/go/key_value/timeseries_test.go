@@ -0,0 +1,34 @@
+package key_value_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestTimeSeriesBucketsBefore1970(t *testing.T) {
+	store := kvtest.NewMemStore()
+	ts := key_value.NewTimeSeries(store, time.Minute)
+	epoch := time.Unix(0, 0)
+	before, after := epoch.Add(-30*time.Second), epoch.Add(30*time.Second)
+	for _, at := range []time.Time{before, after} {
+		if err := ts.Record("m", at, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range []string{key_value.TimeSeriesPrefix + "m:-1", key_value.TimeSeriesPrefix + "m:0"} {
+		if exists, _ := store.Exists(key); !exists {
+			t.Errorf("%s does not exist, want one point in each bucket either side of 1970", key)
+		}
+	}
+	points, err := ts.Range("m", epoch.Add(-time.Minute), epoch.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || !points[0].Time.Equal(before) {
+		t.Errorf("Range before 1970 = %v, want the one point at %v", points, before)
+	}
+}
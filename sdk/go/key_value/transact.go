@@ -0,0 +1,111 @@
+package key_value
+
+import (
+	"errors"
+	"sort"
+)
+
+// Tx is the view of a store inside Transact. Reads are served from the store
+// the first time a key is read and from memory after that, and see the
+// transaction's own staged writes. Writes are staged in memory until the
+// transaction commits.
+type Tx struct {
+	store  KV
+	reads  map[string]txValue
+	writes map[string]txValue
+}
+
+type txValue struct {
+	value  []byte
+	exists bool
+}
+
+// Transact runs fn with a Tx over store, then, if fn returns nil, applies
+// every write fn staged, and returns fn's error or the first error applying
+// the writes. It gives a "read several keys, compute, write several keys"
+// block a single commit point.
+//
+// It is not a transaction on the host. Reads are not isolated from writes
+// made by others while fn runs, and nothing checks at commit that the keys
+// read are unchanged, so concurrent updates can be overwritten. Staged sets
+// are applied with MultiSetAtomic, which tries to undo them if one fails;
+// staged deletes are applied after the sets, one at a time, and a failure
+// among them leaves the earlier deletes and all the sets in place.
+func Transact(store KV, fn func(tx *Tx) error) error {
+	tx := &Tx{store: store, reads: make(map[string]txValue), writes: make(map[string]txValue)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+func (tx *Tx) read(key string) (txValue, error) {
+	if v, ok := tx.writes[key]; ok {
+		return v, nil
+	}
+	if v, ok := tx.reads[key]; ok {
+		return v, nil
+	}
+	value, err := tx.store.Get(key)
+	var v txValue
+	switch {
+	case err == nil:
+		v = txValue{value: value, exists: true}
+	case !errors.Is(err, ErrorNoSuchKey):
+		return txValue{}, err
+	}
+	tx.reads[key] = v
+	return v, nil
+}
+
+// Get returns the value of key as seen by the transaction, or ErrorNoSuchKey.
+func (tx *Tx) Get(key string) ([]byte, error) {
+	v, err := tx.read(key)
+	if err != nil {
+		return nil, err
+	}
+	if !v.exists {
+		return []byte{}, ErrorNoSuchKey
+	}
+	return append([]byte{}, v.value...), nil
+}
+
+// Exists reports whether key exists as seen by the transaction.
+func (tx *Tx) Exists(key string) (bool, error) {
+	v, err := tx.read(key)
+	return v.exists, err
+}
+
+// Set stages setting key to value.
+func (tx *Tx) Set(key string, value []byte) {
+	tx.writes[key] = txValue{value: append([]byte{}, value...), exists: true}
+}
+
+// Delete stages deleting key.
+func (tx *Tx) Delete(key string) {
+	tx.writes[key] = txValue{}
+}
+
+func (tx *Tx) commit() error {
+	sets := make(map[string][]byte)
+	var deletes []string
+	for key, v := range tx.writes {
+		if v.exists {
+			sets[key] = v.value
+		} else {
+			deletes = append(deletes, key)
+		}
+	}
+	if len(sets) > 0 {
+		if err := MultiSetAtomic(tx.store, sets); err != nil {
+			return err
+		}
+	}
+	sort.Strings(deletes)
+	for _, key := range deletes {
+		if err := tx.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestTransact(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("from", []byte("10"))
+	store.Set("old", []byte("x"))
+
+	err := key_value.Transact(store, func(tx *key_value.Tx) error {
+		from, err := tx.Get("from")
+		if err != nil {
+			return err
+		}
+		tx.Set("to", from)
+		tx.Delete("from")
+		tx.Delete("old")
+
+		if ok, err := tx.Exists("from"); err != nil || ok {
+			t.Errorf("Exists of staged delete = %v, %v, want false", ok, err)
+		}
+		if value, err := tx.Get("to"); err != nil || string(value) != "10" {
+			t.Errorf("Get of staged set = %q, %v, want 10", value, err)
+		}
+		if ok, _ := store.Exists("to"); ok {
+			t.Error("staged set reached the store before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"from": false, "old": false, "to": true} {
+		if ok, err := store.Exists(key); err != nil || ok != want {
+			t.Errorf("Exists(%q) = %v, %v after commit, want %v", key, ok, err, want)
+		}
+	}
+}
+
+func TestTransactAbort(t *testing.T) {
+	store := kvtest.NewMemStore()
+	errAbort := errors.New("abort")
+	err := key_value.Transact(store, func(tx *key_value.Tx) error {
+		tx.Set("k", []byte("v"))
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Transact = %v, want fn's error", err)
+	}
+	if ok, _ := store.Exists("k"); ok {
+		t.Error("aborted transaction wrote to the store")
+	}
+}
+
+func TestTransactRollsBackFailedWrite(t *testing.T) {
+	store := &failingStore{MemStore: kvtest.NewMemStore(), failKey: "b"}
+	store.MemStore.Set("a", []byte("old a"))
+
+	err := key_value.Transact(store, func(tx *key_value.Tx) error {
+		tx.Set("a", []byte("new a"))
+		tx.Set("b", []byte("new b"))
+		tx.Delete("c")
+		return nil
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Transact = %v, want the failed write's error", err)
+	}
+	if value, err := store.Get("a"); err != nil || string(value) != "old a" {
+		t.Errorf("a = %q, %v after rollback, want old a", value, err)
+	}
+	if ok, _ := store.Exists("b"); ok {
+		t.Error("b exists after rollback")
+	}
+}
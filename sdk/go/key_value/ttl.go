@@ -0,0 +1,173 @@
+package key_value
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ttlMagic marks a value written by SetWithTTL. It is followed by the expiry
+// as 8 bytes of big-endian Unix nanoseconds, and then the value itself.
+const (
+	ttlMagic     = "\x00ttl\x01"
+	ttlHeaderLen = len(ttlMagic) + 8
+)
+
+// SetWithTTL stores value at key so that GetWithTTL reports it missing once
+// ttl has passed. The expiry is kept in a header in front of the value, so
+// it is written together with the value in a single Set and can never fall
+// out of step with it.
+//
+// Values written this way carry the header: a plain Get returns it along
+// with the value, so they must be read with GetWithTTL.
+func SetWithTTL(store KV, key string, value []byte, ttl time.Duration) error {
+	return store.Set(key, withTTLHeader(value, time.Now().Add(ttl)))
+}
+
+// GetWithTTL returns the value stored at key by SetWithTTL. If the value has
+// expired it is deleted and ErrorNoSuchKey returned. A value without the
+// header, as written by Set, is returned unchanged and never expires.
+//
+// Nothing deletes expired values until they are read. The lazy delete is a
+// separate host call from the read, so a value written at the same time by
+// another instance can be deleted along with the expired one.
+func GetWithTTL(store KV, key string) ([]byte, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return value, err
+	}
+	payload, expires, ok := splitTTLHeader(value)
+	if !ok {
+		return value, nil
+	}
+	if !time.Now().Before(expires) {
+		if err := store.Delete(key); err != nil {
+			return []byte{}, err
+		}
+		return []byte{}, ErrorNoSuchKey
+	}
+	return payload, nil
+}
+
+func withTTLHeader(value []byte, expires time.Time) []byte {
+	buf := make([]byte, ttlHeaderLen+len(value))
+	copy(buf, ttlMagic)
+	binary.BigEndian.PutUint64(buf[len(ttlMagic):], uint64(expires.UnixNano()))
+	copy(buf[ttlHeaderLen:], value)
+	return buf
+}
+
+func splitTTLHeader(value []byte) ([]byte, time.Time, bool) {
+	if len(value) < ttlHeaderLen || string(value[:len(ttlMagic)]) != ttlMagic {
+		return value, time.Time{}, false
+	}
+	expires := int64(binary.BigEndian.Uint64(value[len(ttlMagic):ttlHeaderLen]))
+	return value[ttlHeaderLen:], time.Unix(0, expires), true
+}
+
+// TTLFormat is a way of recording when a value expires.
+type TTLFormat int
+
+const (
+	// TTLHeader keeps the expiry in a header in front of the value, as
+	// written by SetWithTTL.
+	TTLHeader TTLFormat = iota
+	// TTLSiblingKey keeps the value unchanged and its expiry in a separate
+	// key, TTLPrefix+key, holding Unix nanoseconds as written by SetInt64.
+	TTLSiblingKey
+)
+
+// TTLPrefix is prepended to a key to form the key holding its expiry in the
+// TTLSiblingKey format.
+const TTLPrefix = "ttl:"
+
+// MigrateTTLFormat rewrites every value with an expiry recorded in the from
+// format to record it in the to format instead, keeping the same expiry, and
+// returns how many it rewrote. Values with no expiry are left alone, expired
+// ones are migrated as they are.
+//
+// Each value is rewritten with separate host calls, writing the new record
+// before removing the old, so an interrupted migration can be resumed by
+// calling MigrateTTLFormat again. Values must not be written in the from
+// format while it runs.
+func MigrateTTLFormat(store KV, from, to TTLFormat) (int, error) {
+	switch {
+	case from == to:
+		return 0, nil
+	case from == TTLSiblingKey && to == TTLHeader:
+		return migrateSiblingToHeader(store)
+	case from == TTLHeader && to == TTLSiblingKey:
+		return migrateHeaderToSibling(store)
+	default:
+		return 0, fmt.Errorf("unknown TTL format migration from %d to %d", from, to)
+	}
+}
+
+func migrateSiblingToHeader(store KV) (int, error) {
+	metaKeys, err := keysWithPrefix(store, TTLPrefix)
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, metaKey := range metaKeys {
+		key := strings.TrimPrefix(metaKey, TTLPrefix)
+		expires, err := GetInt64(store, metaKey)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return migrated, err
+		}
+		value, err := store.Get(key)
+		switch {
+		case errors.Is(err, ErrorNoSuchKey):
+			// The expiry outlived its value; there is nothing to migrate.
+		case err != nil:
+			return migrated, err
+		default:
+			// A value that already has a header was migrated by an earlier,
+			// interrupted run.
+			if _, _, ok := splitTTLHeader(value); !ok {
+				if err := store.Set(key, withTTLHeader(value, time.Unix(0, expires))); err != nil {
+					return migrated, err
+				}
+				migrated++
+			}
+		}
+		if err := store.Delete(metaKey); err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+func migrateHeaderToSibling(store KV) (int, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, key := range keys {
+		value, err := store.Get(key)
+		if errors.Is(err, ErrorNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return migrated, err
+		}
+		payload, expires, ok := splitTTLHeader(value)
+		if !ok {
+			continue
+		}
+		if err := SetInt64(store, TTLPrefix+key, expires.UnixNano()); err != nil {
+			return migrated, err
+		}
+		if err := store.Set(key, payload); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,154 @@
+package key_value_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestMigrateTTLFormatHeaderToSibling(t *testing.T) {
+	store := kvtest.NewMemStore()
+	before := time.Now()
+	if err := key_value.SetWithTTL(store, "a", []byte("value a"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+	store.Set("plain", []byte("no expiry"))
+
+	migrated, err := key_value.MigrateTTLFormat(store, key_value.TTLHeader, key_value.TTLSiblingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 1 {
+		t.Errorf("migrated %d values, want 1", migrated)
+	}
+
+	got, err := store.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "value a" {
+		t.Errorf("a = %q after migration, want %q", got, "value a")
+	}
+	expires, err := key_value.GetInt64(store, key_value.TTLPrefix+"a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi := before.Add(time.Hour).UnixNano(), after.Add(time.Hour).UnixNano(); expires < lo || expires > hi {
+		t.Errorf("expiry of a = %d, want between %d and %d", expires, lo, hi)
+	}
+	if exists, _ := store.Exists(key_value.TTLPrefix + "plain"); exists {
+		t.Error("migration gave a value with no expiry a sibling key")
+	}
+}
+
+func TestMigrateTTLFormatRoundTrip(t *testing.T) {
+	store := kvtest.NewMemStore()
+	live := time.Now().Add(time.Hour).UnixNano()
+	expired := time.Now().Add(-time.Hour).UnixNano()
+	store.Set("live", []byte("live value"))
+	key_value.SetInt64(store, key_value.TTLPrefix+"live", live)
+	store.Set("expired", []byte("expired value"))
+	key_value.SetInt64(store, key_value.TTLPrefix+"expired", expired)
+	key_value.SetInt64(store, key_value.TTLPrefix+"orphan", live)
+
+	migrated, err := key_value.MigrateTTLFormat(store, key_value.TTLSiblingKey, key_value.TTLHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 2 {
+		t.Errorf("migrated %d values to headers, want 2", migrated)
+	}
+	for _, key := range []string{"live", "expired", "orphan"} {
+		if exists, _ := store.Exists(key_value.TTLPrefix + key); exists {
+			t.Errorf("sibling key of %s still exists after migrating to headers", key)
+		}
+	}
+	got, err := key_value.GetWithTTL(store, "live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "live value" {
+		t.Errorf("GetWithTTL(live) = %q, want %q", got, "live value")
+	}
+
+	migrated, err = key_value.MigrateTTLFormat(store, key_value.TTLHeader, key_value.TTLSiblingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 2 {
+		t.Errorf("migrated %d values back to sibling keys, want 2", migrated)
+	}
+	for key, want := range map[string]int64{"live": live, "expired": expired} {
+		expires, err := key_value.GetInt64(store, key_value.TTLPrefix+key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expires != want {
+			t.Errorf("expiry of %s = %d after the round trip, want %d", key, expires, want)
+		}
+	}
+	if exists, _ := store.Exists("orphan"); exists {
+		t.Error("migration created a value for an expiry with none")
+	}
+}
+
+func TestMigrateTTLFormatResumes(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to key_value.TTLFormat
+	}{
+		{"sibling to header", key_value.TTLSiblingKey, key_value.TTLHeader},
+		{"header to sibling", key_value.TTLHeader, key_value.TTLSiblingKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &failingStore{MemStore: kvtest.NewMemStore()}
+			want := map[string]int64{}
+			for _, key := range []string{"a", "b", "c"} {
+				want[key] = time.Now().Add(time.Hour).UnixNano()
+				store.Set(key, []byte("value "+key))
+				key_value.SetInt64(store, key_value.TTLPrefix+key, want[key])
+			}
+			if tt.from == key_value.TTLHeader {
+				if _, err := key_value.MigrateTTLFormat(store, key_value.TTLSiblingKey, key_value.TTLHeader); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			store.failKey = "b"
+			if _, err := key_value.MigrateTTLFormat(store, tt.from, tt.to); !errors.Is(err, errInjected) {
+				t.Fatalf("interrupted MigrateTTLFormat = %v, want %v", err, errInjected)
+			}
+			store.failKey = ""
+			if _, err := key_value.MigrateTTLFormat(store, tt.from, tt.to); err != nil {
+				t.Fatalf("resumed MigrateTTLFormat: %v", err)
+			}
+			if tt.to == key_value.TTLHeader {
+				if _, err := key_value.MigrateTTLFormat(store, key_value.TTLHeader, key_value.TTLSiblingKey); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for key, expiry := range want {
+				got, err := store.Get(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != "value "+key {
+					t.Errorf("%s = %q after resuming, want %q", key, got, "value "+key)
+				}
+				expires, err := key_value.GetInt64(store, key_value.TTLPrefix+key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expires != expiry {
+					t.Errorf("expiry of %s = %d after resuming, want %d", key, expires, expiry)
+				}
+			}
+		})
+	}
+}
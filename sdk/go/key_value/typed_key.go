@@ -0,0 +1,59 @@
+package key_value
+
+// Key is a typed handle to a single well-known key, such as a configuration
+// object or a counter, binding the key's name to the type stored at it and
+// the codec that encodes it. Defining the key once keeps call sites from
+// misspelling it or storing the wrong type.
+type Key[T any] struct {
+	store KV
+	name  string
+	codec Codec
+}
+
+// DefineKey returns a Key for name in store whose values are encoded as JSON,
+// with the options of WithJSONOptions if store was wrapped by it.
+func DefineKey[T any](store KV, name string) Key[T] {
+	return DefineKeyCodec[T](store, name, jsonCodecFor(store))
+}
+
+// DefineKeyCodec returns a Key for name in store whose values are encoded
+// with codec.
+func DefineKeyCodec[T any](store KV, name string, codec Codec) Key[T] {
+	return Key[T]{store: store, name: name, codec: codec}
+}
+
+// Name returns the key's name.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Get decodes the value stored at the key. It returns ErrorNoSuchKey if the
+// key does not exist.
+func (k Key[T]) Get() (T, error) {
+	var v T
+	value, err := k.store.Get(k.name)
+	if err != nil {
+		return v, err
+	}
+	err = k.codec.Unmarshal(value, &v)
+	return v, err
+}
+
+// Set encodes v and stores it at the key.
+func (k Key[T]) Set(v T) error {
+	value, err := k.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return k.store.Set(k.name, value)
+}
+
+// Delete deletes the key.
+func (k Key[T]) Delete() error {
+	return k.store.Delete(k.name)
+}
+
+// Exists reports whether the key exists.
+func (k Key[T]) Exists() (bool, error) {
+	return k.store.Exists(k.name)
+}
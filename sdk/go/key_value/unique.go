@@ -0,0 +1,85 @@
+package key_value
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetUnique stores value at valueKey and reserves it at indexPrefix+value,
+// which holds valueKey, so that no two keys written with SetUnique under the
+// same indexPrefix hold the same value, as for unique usernames or email
+// addresses. If another key already claims value, an error matching
+// ErrDuplicateIndex is returned and nothing is written. A previous value of
+// valueKey is released once the new one is stored.
+//
+// The check and the reservation are separate host calls, so two instances
+// claiming the same value at once can both succeed, the later reservation
+// winning. The reservation outlives the primary key if it is deleted any
+// other way than with DeleteUnique.
+func SetUnique(store KV, valueKey, value string, indexPrefix string) error {
+	old, hadOld, err := claimUnique(store, valueKey, value, indexPrefix)
+	if err != nil {
+		return err
+	}
+	// The new reservation's lock has been released, so that releasing the
+	// old one never holds two reservation locks at once.
+	if hadOld && string(old) != value {
+		return releaseUnique(store, valueKey, string(old), indexPrefix)
+	}
+	return nil
+}
+
+// claimUnique reserves value for valueKey and stores it there, returning the
+// value valueKey held before.
+func claimUnique(store KV, valueKey, value string, indexPrefix string) ([]byte, bool, error) {
+	defer lockKey(store, indexPrefix+value)()
+
+	owner, ok, err := GetOK(store, indexPrefix+value)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok && string(owner) != valueKey {
+		return nil, false, fmt.Errorf("%w: %q is used by %q", ErrDuplicateIndex, value, owner)
+	}
+	old, hadOld, err := GetOK(store, valueKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !ok {
+		if err := store.Set(indexPrefix+value, []byte(valueKey)); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := store.Set(valueKey, []byte(value)); err != nil {
+		return nil, false, err
+	}
+	return old, hadOld, nil
+}
+
+// DeleteUnique deletes valueKey, written with SetUnique, and releases its
+// value's reservation under indexPrefix.
+func DeleteUnique(store KV, valueKey, indexPrefix string) error {
+	value, ok, err := GetOK(store, valueKey)
+	if err != nil || !ok {
+		return err
+	}
+	if err := store.Delete(valueKey); err != nil {
+		return err
+	}
+	return releaseUnique(store, valueKey, string(value), indexPrefix)
+}
+
+// releaseUnique deletes the reservation of value if valueKey holds it.
+func releaseUnique(store KV, valueKey, value, indexPrefix string) error {
+	defer lockKey(store, indexPrefix+value)()
+
+	owner, err := store.Get(indexPrefix + value)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil
+	}
+	if err != nil || string(owner) != valueKey {
+		return err
+	}
+	return store.Delete(indexPrefix + value)
+}
@@ -0,0 +1,113 @@
+package key_value_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestSetUnique(t *testing.T) {
+	store := kvtest.NewMemStore()
+	const index = "email:"
+
+	if err := key_value.SetUnique(store, "user:1", "a@example.com", index); err != nil {
+		t.Fatal(err)
+	}
+	err := key_value.SetUnique(store, "user:2", "a@example.com", index)
+	if !errors.Is(err, key_value.ErrDuplicateIndex) {
+		t.Fatalf("SetUnique of a taken value = %v, want ErrDuplicateIndex", err)
+	}
+	if ok, _ := store.Exists("user:2"); ok {
+		t.Error("a rejected SetUnique wrote the key")
+	}
+	if err := key_value.SetUnique(store, "user:1", "a@example.com", index); err != nil {
+		t.Errorf("SetUnique of a key's own value: %v", err)
+	}
+
+	// Changing the value releases the old one.
+	if err := key_value.SetUnique(store, "user:1", "b@example.com", index); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := store.Exists(index + "a@example.com"); ok {
+		t.Error("the old value is still reserved")
+	}
+	if err := key_value.SetUnique(store, "user:2", "a@example.com", index); err != nil {
+		t.Errorf("SetUnique of a released value: %v", err)
+	}
+
+	if err := key_value.DeleteUnique(store, "user:1", index); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"user:1", index + "b@example.com"} {
+		if ok, _ := store.Exists(key); ok {
+			t.Errorf("%q exists after DeleteUnique", key)
+		}
+	}
+	if err := key_value.DeleteUnique(store, "user:1", index); err != nil {
+		t.Errorf("DeleteUnique of a missing key: %v", err)
+	}
+}
+
+// interleavingStore pauses the first write of "x" to "k" until someone else
+// has read "k", so that a second SetUnique of the key starts in the middle of
+// the first.
+type interleavingStore struct {
+	*kvtest.MemStore
+	applied, read     chan struct{}
+	appliedX, readOne sync.Once
+}
+
+func (s *interleavingStore) Set(key string, value []byte) error {
+	err := s.MemStore.Set(key, value)
+	if key == "k" && string(value) == "x" {
+		s.appliedX.Do(func() {
+			close(s.applied)
+			<-s.read
+		})
+	}
+	return err
+}
+
+func (s *interleavingStore) Get(key string) ([]byte, error) {
+	value, err := s.MemStore.Get(key)
+	if key == "k" {
+		select {
+		case <-s.applied:
+			s.readOne.Do(func() { close(s.read) })
+		default:
+		}
+	}
+	return value, err
+}
+
+func TestSetUniqueOpposingChangesWithKeyLocking(t *testing.T) {
+	base := &interleavingStore{MemStore: kvtest.NewMemStore(), applied: make(chan struct{}), read: make(chan struct{})}
+	store := key_value.WithKeyLocking(base)
+	if err := key_value.SetUnique(store, "k", "y", "idx:"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first call moves k from y to x and the second back to y, so each
+	// releases the reservation the other is claiming.
+	done := make(chan struct{}, 2)
+	go func() {
+		key_value.SetUnique(store, "k", "x", "idx:")
+		done <- struct{}{}
+	}()
+	<-base.applied
+	go func() {
+		key_value.SetUnique(store, "k", "y", "idx:")
+		done <- struct{}{}
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("opposing SetUnique calls deadlocked")
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package key_value
+
+// WithValidator returns store wrapped so that every Set through it first
+// calls validate with the key and value, and returns validate's error,
+// without writing, if it fails. It lets an application enforce invariants,
+// such as values being valid JSON or under a size, in one place. Other
+// operations pass straight through to store.
+//
+// The helpers of this package that encode values, such as SetJSON, SetInt64
+// and SetWithTTL, call Set with the encoded bytes, so validate sees exactly
+// what would be stored, including any header those helpers add.
+func WithValidator(store KV, validate func(key string, value []byte) error) KV {
+	return &validator{KV: store, validate: validate}
+}
+
+type validator struct {
+	KV
+	validate func(key string, value []byte) error
+}
+
+func (v *validator) unwrap() KV {
+	return v.KV
+}
+
+func (v *validator) Set(key string, value []byte) error {
+	if err := v.validate(key, value); err != nil {
+		return err
+	}
+	return v.KV.Set(key, value)
+}
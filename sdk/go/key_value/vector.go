@@ -0,0 +1,169 @@
+package key_value
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// VersionVector counts the updates each node has made to a value. Nodes are
+// named by the caller, such as by region or store.
+type VersionVector map[string]uint64
+
+// Descends reports whether vv has seen every update other has, that is
+// whether vv is at least other for every node.
+func (vv VersionVector) Descends(other VersionVector) bool {
+	for node, n := range other {
+		if vv[node] < n {
+			return false
+		}
+	}
+	return true
+}
+
+// Merged returns a new vector holding, for every node, the larger count of vv
+// and other.
+func (vv VersionVector) Merged(other VersionVector) VersionVector {
+	merged := make(VersionVector, len(vv))
+	for node, n := range vv {
+		merged[node] = n
+	}
+	for node, n := range other {
+		if n > merged[node] {
+			merged[node] = n
+		}
+	}
+	return merged
+}
+
+// ConflictFunc resolves concurrent updates of key, where neither version
+// vector descends from the other, and returns the value to keep. It must be
+// deterministic, and give the same result with its arguments swapped, for
+// every store to converge on the same value.
+type ConflictFunc func(key string, local []byte, localVV VersionVector, incoming []byte, incomingVV VersionVector) ([]byte, error)
+
+// VectorStore keeps values alongside version vectors so that updates made
+// concurrently by several writers, such as components syncing state across
+// stores in different regions, can be merged without losing any.
+//
+// Each value is stored at its key as a JSON document of the form
+//
+//	{"vv": {"<node>": <count>, …}, "value": "<base64>"}
+//
+// so keys written through a VectorStore must only be read through one.
+type VectorStore struct {
+	store   KV
+	node    string
+	resolve ConflictFunc
+}
+
+// NewVectorStore returns a VectorStore over store that counts its own writes
+// under node. Concurrent updates are resolved with resolve, or, if it is nil,
+// with LastWriterWinsByNode.
+func NewVectorStore(store KV, node string, resolve ConflictFunc) *VectorStore {
+	if resolve == nil {
+		resolve = LastWriterWinsByNode
+	}
+	return &VectorStore{store: store, node: node, resolve: resolve}
+}
+
+// LastWriterWinsByNode is the default ConflictFunc. Of two concurrent
+// updates it keeps the one whose vector has the higher count for the
+// greatest node name, in byte order, at which the vectors differ, so every
+// store picks the same winner without comparing clocks.
+func LastWriterWinsByNode(key string, local []byte, localVV VersionVector, incoming []byte, incomingVV VersionVector) ([]byte, error) {
+	nodes := sortedKeys(localVV.Merged(incomingVV))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		l, in := localVV[nodes[i]], incomingVV[nodes[i]]
+		if l != in {
+			if in > l {
+				return incoming, nil
+			}
+			return local, nil
+		}
+	}
+	// Equal vectors with different values; break the tie by value.
+	if bytes.Compare(incoming, local) > 0 {
+		return incoming, nil
+	}
+	return local, nil
+}
+
+type vectorRecord struct {
+	VV    VersionVector `json:"vv"`
+	Value []byte        `json:"value"`
+}
+
+// Get returns the value stored at key and its version vector.
+func (s *VectorStore) Get(key string) ([]byte, VersionVector, error) {
+	rec, err := s.load(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rec.Value, rec.VV, nil
+}
+
+// Set writes value to key as a new update by this store's node and returns
+// the value's new version vector.
+func (s *VectorStore) Set(key string, value []byte) (VersionVector, error) {
+	defer lockKey(s.store, key)()
+
+	rec, err := s.load(key)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return nil, err
+	}
+	vv := rec.VV.Merged(nil)
+	vv[s.node]++
+	if err := s.save(key, vectorRecord{VV: vv, Value: value}); err != nil {
+		return nil, err
+	}
+	return vv, nil
+}
+
+// Merge folds in a value for key received from another writer along with
+// its version vector. If vv has seen every update to the stored value,
+// incoming replaces it; if the stored value has seen every update in vv,
+// incoming is out of date and is dropped; otherwise the updates were
+// concurrent and the store's ConflictFunc picks the value to keep, which is
+// stored with the merged vector of both.
+func (s *VectorStore) Merge(key string, incoming []byte, vv VersionVector) error {
+	defer lockKey(s.store, key)()
+
+	rec, err := s.load(key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return s.save(key, vectorRecord{VV: vv, Value: incoming})
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case vv.Descends(rec.VV):
+		return s.save(key, vectorRecord{VV: vv, Value: incoming})
+	case rec.VV.Descends(vv):
+		return nil
+	}
+	value, err := s.resolve(key, rec.Value, rec.VV, incoming, vv)
+	if err != nil {
+		return err
+	}
+	return s.save(key, vectorRecord{VV: rec.VV.Merged(vv), Value: value})
+}
+
+func (s *VectorStore) load(key string) (vectorRecord, error) {
+	var rec vectorRecord
+	raw, err := s.store.Get(key)
+	if err != nil {
+		return rec, err
+	}
+	err = json.Unmarshal(raw, &rec)
+	return rec, err
+}
+
+func (s *VectorStore) save(key string, rec vectorRecord) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(key, raw)
+}
@@ -0,0 +1,51 @@
+package key_value_test
+
+import (
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+func TestVectorStoreMerge(t *testing.T) {
+	east := key_value.NewVectorStore(kvtest.NewMemStore(), "east", nil)
+	west := key_value.NewVectorStore(kvtest.NewMemStore(), "west", nil)
+
+	vv, err := east.Set("k", []byte("one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := west.Merge("k", []byte("one"), vv); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later update replaces the value; replaying an older one does not.
+	vv2, _ := west.Set("k", []byte("two"))
+	if err := east.Merge("k", []byte("two"), vv2); err != nil {
+		t.Fatal(err)
+	}
+	if err := east.Merge("k", []byte("one"), vv); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, _ := east.Get("k"); string(value) != "two" {
+		t.Fatalf("east after merges = %q, want \"two\"", value)
+	}
+
+	// Concurrent updates converge on the same value in both stores.
+	eastVV, _ := east.Set("k", []byte("east"))
+	westVV, _ := west.Set("k", []byte("west"))
+	if err := east.Merge("k", []byte("west"), westVV); err != nil {
+		t.Fatal(err)
+	}
+	if err := west.Merge("k", []byte("east"), eastVV); err != nil {
+		t.Fatal(err)
+	}
+	eastValue, eastMerged, _ := east.Get("k")
+	westValue, westMerged, _ := west.Get("k")
+	if string(eastValue) != string(westValue) {
+		t.Fatalf("stores diverged: east %q, west %q", eastValue, westValue)
+	}
+	if !eastMerged.Descends(eastVV) || !eastMerged.Descends(westVV) || !westMerged.Descends(eastMerged) {
+		t.Fatalf("merged vectors %v and %v do not cover both updates", eastMerged, westMerged)
+	}
+}
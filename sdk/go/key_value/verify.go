@@ -0,0 +1,99 @@
+package key_value
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Problem is a key left inconsistent by an interrupted multi-key write, or no
+// longer needed, as found by Verify.
+type Problem struct {
+	Key    string
+	Reason string
+}
+
+// Verify scans store for the leftovers of the layers in this package that
+// spread one logical value over several keys, or keep metadata in a value:
+//
+//   - a BlobStore manifest that cannot be decoded, or that refers to a chunk
+//     that does not exist, so that its blob cannot be read;
+//   - a BlobStore chunk that no manifest refers to;
+//   - a value written by SetWithTTL whose expiry has passed but which has
+//     not been read, and so not lazily deleted, since.
+//
+// Verify reads every key and value in the store. Run it when nothing else is
+// writing: a BlobStore.PutBlob in progress has chunks that its manifest does
+// not refer to yet.
+func Verify(store KV) ([]Problem, error) {
+	keys, err := store.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+	exists := keySet(keys)
+
+	var problems []Problem
+	referenced := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, BlobPrefix+"manifest:") {
+			continue
+		}
+		raw, err := store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var hashes []string
+		if err := json.Unmarshal(raw, &hashes); err != nil {
+			problems = append(problems, Problem{Key: key, Reason: "blob manifest cannot be decoded"})
+			continue
+		}
+		missing := ""
+		for _, hash := range hashes {
+			referenced[blobChunkKey(hash)] = true
+			if !exists[blobChunkKey(hash)] && missing == "" {
+				missing = hash
+			}
+		}
+		if missing != "" {
+			problems = append(problems, Problem{Key: key, Reason: "blob manifest refers to missing chunk " + missing})
+		}
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		if strings.HasPrefix(key, BlobPrefix+"manifest:") {
+			continue
+		}
+		if strings.HasPrefix(key, BlobPrefix+"chunk:") {
+			if !referenced[key] {
+				problems = append(problems, Problem{Key: key, Reason: "blob chunk is not referenced by any manifest"})
+			}
+			continue
+		}
+		value, err := store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, expires, ok := splitTTLHeader(value); ok && !now.Before(expires) {
+			problems = append(problems, Problem{Key: key, Reason: "value expired at " + expires.Format(time.RFC3339)})
+		}
+	}
+	return problems, nil
+}
+
+// Repair deletes the key of every problem Verify finds and returns the
+// problems it repaired. A blob whose manifest refers to a missing chunk
+// cannot be recovered, so its manifest is deleted; the chunks it did have
+// are deleted by the next Repair, once nothing refers to them.
+func Repair(store KV) ([]Problem, error) {
+	problems, err := Verify(store)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range problems {
+		if err := store.Delete(p.Key); err != nil {
+			return problems[:i], err
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,91 @@
+package key_value
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is returned by SetVersioned when the stored version does
+// not match the expected one.
+var ErrVersionConflict = errors.New("version conflict")
+
+// The size of the version header written by SetVersioned.
+const versionHeaderLen = 8
+
+// GetVersioned returns a value written by SetVersioned along with its version.
+// ErrorNoSuchKey is returned if the key does not exist.
+func GetVersioned(store KV, key string) ([]byte, uint64, error) {
+	raw, err := store.Get(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decodeVersioned(key, raw)
+}
+
+// SetVersioned writes value to key only if the stored version equals expected,
+// and returns the new version. An expected version of zero means the key must
+// not exist yet. ErrVersionConflict is returned if the versions differ.
+//
+// Versioned values are stored as an 8-byte big-endian version followed by the
+// value, so they must only be read with GetVersioned. The host has no
+// conditional write, so the version check and the write are separate calls;
+// a writer in another instance can still slip in between them.
+func SetVersioned(store KV, key string, value []byte, expected uint64) (uint64, error) {
+	defer lockKey(store, key)()
+	return setVersioned(store, key, value, expected)
+}
+
+func setVersioned(store KV, key string, value []byte, expected uint64) (uint64, error) {
+	_, current, err := GetVersioned(store, key)
+	if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+		return 0, err
+	}
+	if current != expected {
+		return 0, ErrVersionConflict
+	}
+
+	next := current + 1
+	raw := make([]byte, versionHeaderLen+len(value))
+	binary.BigEndian.PutUint64(raw, next)
+	copy(raw[versionHeaderLen:], value)
+	if err := store.Set(key, raw); err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+func decodeVersioned(key string, raw []byte) ([]byte, uint64, error) {
+	if len(raw) < versionHeaderLen {
+		return nil, 0, fmt.Errorf("value at %q is too short to be versioned", key)
+	}
+	return raw[versionHeaderLen:], binary.BigEndian.Uint64(raw), nil
+}
+
+// UpdateWithRetry applies fn to the versioned value at key and writes the
+// result with SetVersioned, retrying from a fresh read up to attempts times
+// when another writer changes the value first. fn is passed the current value
+// and whether the key existed. ErrVersionConflict is returned if every
+// attempt conflicts; an error from fn is returned as is.
+func UpdateWithRetry(store KV, key string, attempts int, fn func(old []byte, existed bool) ([]byte, error)) error {
+	defer lockKey(store, key)()
+
+	for i := 0; i < attempts; i++ {
+		old, version, err := GetVersioned(store, key)
+		existed := err == nil
+		if err != nil && !errors.Is(err, ErrorNoSuchKey) {
+			return err
+		}
+
+		value, err := fn(old, existed)
+		if err != nil {
+			return err
+		}
+
+		_, err = setVersioned(store, key, value, version)
+		if !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+	return ErrVersionConflict
+}
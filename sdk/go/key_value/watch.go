@@ -0,0 +1,161 @@
+package key_value
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var errWatchInterval = errors.New("watch interval must be positive")
+
+// WatchEvent reports a change to a watched key.
+type WatchEvent struct {
+	// Value is the key's new value, or nil if Deleted.
+	Value []byte
+	// Deleted is true if the key no longer exists.
+	Deleted bool
+}
+
+// Watch polls key every interval and sends an event on the returned channel
+// each time its value changes or it is deleted or created. The value at the
+// time Watch is called is the starting point and is not sent. The channel is
+// closed once ctx is done.
+//
+// The host cannot notify of changes, so Watch reads the key on every tick:
+// each watcher costs one host call per interval, and changes that are undone
+// within one interval go unseen. An interval of a few seconds is plenty for
+// reloading configuration. Errors while polling are ignored and the key is
+// read again on the next tick; an error reading the starting value is
+// returned, as is one for an interval that is not positive.
+func Watch(ctx context.Context, store KV, key string, interval time.Duration) (<-chan WatchEvent, error) {
+	if interval <= 0 {
+		return nil, errWatchInterval
+	}
+	last, exists, err := GetIfExists(store, key)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			value, ok, err := GetIfExists(store, key)
+			if err != nil || (ok == exists && bytes.Equal(value, last)) {
+				continue
+			}
+			last, exists = value, ok
+
+			event := WatchEvent{Deleted: !ok}
+			if ok {
+				// The receiver owns the slice it is sent.
+				event.Value = append([]byte{}, value...)
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// PrefixEvent reports keys that appeared under or disappeared from a watched
+// prefix. Both lists are sorted.
+type PrefixEvent struct {
+	Added   []string
+	Removed []string
+}
+
+// WatchPrefix polls the keys starting with prefix every interval and sends an
+// event on the returned channel when keys are added or removed. Only changes
+// to the set of keys are reported, never changes to values. The keys present
+// when WatchPrefix is called are the starting point and are not sent. The
+// channel is closed once ctx is done.
+//
+// Changes are coalesced: an event describes the net difference since the
+// previous event was received, so a key added and removed again before the
+// receiver is ready is not reported at all. Each poll lists every key in the
+// store; as with Watch, polling errors are ignored and an interval that is
+// not positive is an error.
+func WatchPrefix(ctx context.Context, store KV, prefix string, interval time.Duration) (<-chan PrefixEvent, error) {
+	if interval <= 0 {
+		return nil, errWatchInterval
+	}
+	keys, err := keysWithPrefix(store, prefix)
+	if err != nil {
+		return nil, err
+	}
+	emitted := keySet(keys)
+
+	events := make(chan PrefixEvent)
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// out is nil, disabling the send, while no change is pending.
+		var out chan<- PrefixEvent
+		var pending PrefixEvent
+		var pendingSet map[string]bool
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- pending:
+				emitted = pendingSet
+				out = nil
+			case <-ticker.C:
+				keys, err := keysWithPrefix(store, prefix)
+				if err != nil {
+					continue
+				}
+				pendingSet = keySet(keys)
+				pending = diffKeySets(emitted, pendingSet)
+				out = nil
+				if len(pending.Added) > 0 || len(pending.Removed) > 0 {
+					out = events
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+func diffKeySets(old, current map[string]bool) PrefixEvent {
+	var event PrefixEvent
+	for key := range current {
+		if !old[key] {
+			event.Added = append(event.Added, key)
+		}
+	}
+	for key := range old {
+		if !current[key] {
+			event.Removed = append(event.Removed, key)
+		}
+	}
+	sort.Strings(event.Added)
+	sort.Strings(event.Removed)
+	return event
+}
@@ -0,0 +1,132 @@
+package key_value_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+// The polling interval of the watch tests.
+const watchInterval = time.Millisecond
+
+func TestWatch(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("config", []byte("v1"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := key_value.Watch(ctx, store, "config", watchInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() key_value.WatchEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return key_value.WatchEvent{}
+		}
+	}
+
+	store.Set("config", []byte("v2"))
+	if event := next(); event.Deleted || string(event.Value) != "v2" {
+		t.Errorf("event after change = %+v, want v2", event)
+	}
+	store.Delete("config")
+	if event := next(); !event.Deleted || event.Value != nil {
+		t.Errorf("event after delete = %+v, want Deleted", event)
+	}
+	store.Set("config", []byte{})
+	if event := next(); event.Deleted || event.Value == nil || len(event.Value) != 0 {
+		t.Errorf("event after creation = %+v, want an empty value", event)
+	}
+
+	cancel()
+	waitClosed(t, events)
+}
+
+// waitClosed drains events until it is closed.
+func waitClosed[T any](t *testing.T, events <-chan T) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+}
+
+func TestWatchInterval(t *testing.T) {
+	if _, err := key_value.Watch(context.Background(), kvtest.NewMemStore(), "k", 0); err == nil {
+		t.Error("Watch with a zero interval succeeded")
+	}
+}
+
+func TestWatchPrefix(t *testing.T) {
+	store := kvtest.NewMemStore()
+	store.Set("job:existing", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := key_value.WatchPrefix(ctx, store, "job:", watchInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() key_value.PrefixEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return key_value.PrefixEvent{}
+		}
+	}
+
+	store.Set("job:a", nil)
+	store.Set("other", nil)
+	if event := next(); !reflect.DeepEqual(event, key_value.PrefixEvent{Added: []string{"job:a"}}) {
+		t.Errorf("event after add = %+v, want job:a added", event)
+	}
+
+	// A value change is not reported, so the next event is the removal.
+	store.Set("job:a", []byte("changed"))
+	time.Sleep(20 * watchInterval)
+	store.Delete("job:existing")
+	if event := next(); !reflect.DeepEqual(event, key_value.PrefixEvent{Removed: []string{"job:existing"}}) {
+		t.Errorf("event after delete = %+v, want job:existing removed", event)
+	}
+
+	// Changes made while the receiver is busy are coalesced into their net
+	// difference.
+	store.Set("job:b", nil)
+	time.Sleep(20 * watchInterval)
+	store.Delete("job:b")
+	store.Delete("job:a")
+	store.Set("job:c", nil)
+	time.Sleep(20 * watchInterval)
+	want := key_value.PrefixEvent{Added: []string{"job:c"}, Removed: []string{"job:a"}}
+	if event := next(); !reflect.DeepEqual(event, want) {
+		t.Errorf("coalesced event = %+v, want %+v", event, want)
+	}
+
+	cancel()
+	waitClosed(t, events)
+}
+
+func TestWatchPrefixInterval(t *testing.T) {
+	if _, err := key_value.WatchPrefix(context.Background(), kvtest.NewMemStore(), "job:", -time.Second); err == nil {
+		t.Error("WatchPrefix with a negative interval succeeded")
+	}
+}
@@ -0,0 +1,204 @@
+package key_value
+
+import (
+	"sync"
+	"time"
+)
+
+type writeBehind struct {
+	store      KV
+	maxPending int
+
+	mu       sync.Mutex
+	pending  map[string]pendingWrite
+	inflight map[string]pendingWrite
+	flushErr error
+
+	// flushMu keeps a single batch in flight, so that an older batch cannot
+	// land after a newer one.
+	flushMu sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type pendingWrite struct {
+	value   []byte
+	deleted bool
+}
+
+// WriteBehind returns a KV that buffers Set and Delete in memory and applies
+// them to store later, along with a function that stops buffering, flushes
+// everything pending and returns the first error encountered by any flush.
+// Buffered writes are flushed every flushInterval, if it is positive, and
+// whenever maxPending keys have pending writes. Reads through the returned KV
+// see buffered writes before they reach store.
+//
+// Buffering trades durability for fewer host calls. Writes that have not
+// been flushed are lost if the component crashes or returns before the flush
+// function is called, and are invisible to every other handle on the store
+// until then. A Spin component should call the flush function before its
+// handler returns.
+func WriteBehind(store KV, flushInterval time.Duration, maxPending int) (KV, func() error) {
+	w := &writeBehind{
+		store:      store,
+		maxPending: maxPending,
+		pending:    make(map[string]pendingWrite),
+		stop:       make(chan struct{}),
+	}
+	if flushInterval > 0 {
+		go w.run(flushInterval)
+	}
+	return w, w.close
+}
+
+func (w *writeBehind) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *writeBehind) close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	w.flush()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.flushErr
+	w.flushErr = nil
+	return err
+}
+
+// flush applies every pending write. Writes queued while it runs go into a
+// fresh buffer for the next flush, and reads see the batch being applied
+// until it is done. A write that fails is queued again unless it has been
+// superseded in the meantime.
+func (w *writeBehind) flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = make(map[string]pendingWrite)
+	w.inflight = batch
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.inflight = nil
+		w.mu.Unlock()
+	}()
+
+	var first error
+	for key, pw := range batch {
+		var err error
+		if pw.deleted {
+			err = w.store.Delete(key)
+		} else {
+			err = w.store.Set(key, pw.value)
+		}
+		if err == nil {
+			continue
+		}
+
+		w.mu.Lock()
+		if _, ok := w.pending[key]; !ok {
+			w.pending[key] = pw
+		}
+		if w.flushErr == nil {
+			w.flushErr = err
+		}
+		w.mu.Unlock()
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (w *writeBehind) queue(key string, pw pendingWrite) error {
+	w.mu.Lock()
+	w.pending[key] = pw
+	full := w.maxPending > 0 && len(w.pending) >= w.maxPending
+	w.mu.Unlock()
+
+	if full {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *writeBehind) lookup(key string) (pendingWrite, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if pw, ok := w.pending[key]; ok {
+		return pw, true
+	}
+	pw, ok := w.inflight[key]
+	return pw, ok
+}
+
+func (w *writeBehind) Get(key string) ([]byte, error) {
+	if pw, ok := w.lookup(key); ok {
+		if pw.deleted {
+			return []byte{}, ErrorNoSuchKey
+		}
+		return append([]byte{}, pw.value...), nil
+	}
+	return w.store.Get(key)
+}
+
+func (w *writeBehind) Set(key string, value []byte) error {
+	return w.queue(key, pendingWrite{value: append([]byte{}, value...)})
+}
+
+func (w *writeBehind) Delete(key string) error {
+	return w.queue(key, pendingWrite{deleted: true})
+}
+
+func (w *writeBehind) Exists(key string) (bool, error) {
+	if pw, ok := w.lookup(key); ok {
+		return !pw.deleted, nil
+	}
+	return w.store.Exists(key)
+}
+
+func (w *writeBehind) GetKeys() ([]string, error) {
+	keys, err := w.store.GetKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buffered := make(map[string]pendingWrite, len(w.inflight)+len(w.pending))
+	for key, pw := range w.inflight {
+		buffered[key] = pw
+	}
+	for key, pw := range w.pending {
+		buffered[key] = pw
+	}
+
+	listed := make(map[string]bool, len(keys))
+	var result []string
+	for _, key := range keys {
+		listed[key] = true
+		if pw, ok := buffered[key]; ok && pw.deleted {
+			continue
+		}
+		result = append(result, key)
+	}
+	for key, pw := range buffered {
+		if !pw.deleted && !listed[key] {
+			result = append(result, key)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package key_value_test
+
+import (
+	"testing"
+
+	"github.com/fermyon/spin/sdk/go/key_value"
+	"github.com/fermyon/spin/sdk/go/key_value/kvtest"
+)
+
+// blockingStore blocks the first Set of key until release is closed.
+type blockingStore struct {
+	key_value.KV
+	key     string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingStore) Set(key string, value []byte) error {
+	if key == b.key && b.entered != nil {
+		close(b.entered)
+		b.entered = nil
+		<-b.release
+	}
+	return b.KV.Set(key, value)
+}
+
+func TestWriteBehindSetDuringFlush(t *testing.T) {
+	backing := kvtest.NewMemStore()
+	blocking := &blockingStore{
+		KV:      backing,
+		key:     "k",
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	entered := blocking.entered
+	store, flush := key_value.WriteBehind(blocking, 0, 1)
+
+	done := make(chan error, 1)
+	go func() { done <- store.Set("k", []byte("old")) }()
+	<-entered
+
+	if value, err := store.Get("k"); err != nil || string(value) != "old" {
+		t.Errorf("Get during flush = %q, %v, want old", value, err)
+	}
+	if keys, err := store.GetKeys(); err != nil || len(keys) != 1 {
+		t.Errorf("GetKeys during flush = %q, %v, want [k]", keys, err)
+	}
+
+	queued := make(chan error, 1)
+	go func() { queued <- store.Set("k", []byte("new")) }()
+	close(blocking.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-queued; err != nil {
+		t.Fatal(err)
+	}
+	if err := flush(); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := backing.Get("k"); err != nil || string(value) != "new" {
+		t.Errorf("backing Get = %q, %v, want new", value, err)
+	}
+	if value, err := store.Get("k"); err != nil || string(value) != "new" {
+		t.Errorf("Get = %q, %v, want new", value, err)
+	}
+}
@@ -0,0 +1,89 @@
+package key_value
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// WriteTimePrefix is prepended to a key to form the key WithWriteTimestamps
+// records its last write time under.
+const WriteTimePrefix = "wtime:"
+
+// ErrStale is returned by GetFresh when a value was last written longer ago
+// than allowed, or when its write time is unknown.
+var ErrStale = errors.New("value stale")
+
+// WithWriteTimestamps returns store wrapped so that every successful Set of a
+// key also records the time under WriteTimePrefix+key, for LastWrite and
+// GetFresh to tell how old a value is. Deleting a key through it deletes its
+// record too, so a record never outlives its key.
+//
+// Recording costs an extra write on every write, and a record of about 8
+// bytes plus the key per key, so it is opt-in. Records are only written
+// through the returned KV: a key written through another handle keeps the
+// time of its last write through this one.
+func WithWriteTimestamps(store KV) KV {
+	return &writeTimestamps{KV: store}
+}
+
+type writeTimestamps struct {
+	KV
+}
+
+func (w *writeTimestamps) unwrap() KV {
+	return w.KV
+}
+
+func (w *writeTimestamps) Set(key string, value []byte) error {
+	if err := w.KV.Set(key, value); err != nil || strings.HasPrefix(key, WriteTimePrefix) {
+		return err
+	}
+	return SetInt64(w.KV, WriteTimePrefix+key, time.Now().UnixNano())
+}
+
+func (w *writeTimestamps) Delete(key string) error {
+	if err := w.KV.Delete(key); err != nil || strings.HasPrefix(key, WriteTimePrefix) {
+		return err
+	}
+	return w.KV.Delete(WriteTimePrefix + key)
+}
+
+// GetFresh returns the value stored at key if it was written within maxAge,
+// so that a consumer can refuse data, such as cached exchange rates, that
+// has not been refreshed recently. It depends on the write times recorded by
+// WithWriteTimestamps: the value must have been written through a KV
+// returned by it, and ErrStale is returned for a value that is older than
+// maxAge or has no record. ErrorNoSuchKey is returned if the key does not
+// exist.
+func GetFresh(store KV, key string, maxAge time.Duration) ([]byte, error) {
+	value, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	written, err := LastWrite(store, key)
+	if errors.Is(err, ErrorNoSuchKey) {
+		return nil, ErrStale
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(written) > maxAge {
+		return nil, ErrStale
+	}
+	return value, nil
+}
+
+// LastWrite returns when key was last written through a KV returned by
+// WithWriteTimestamps, or ErrorNoSuchKey if it has no record, for staleness
+// diagnostics such as finding keys that have stopped being updated.
+func LastWrite(store KV, key string) (time.Time, error) {
+	if w, ok := findOption[*writeTimestamps](store); ok {
+		store = w.KV
+	}
+	nanos, err := GetInt64(store, WriteTimePrefix+key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}